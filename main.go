@@ -50,6 +50,24 @@ func main() {
 	// viewLoadConfigAstType(rootDir)
 	// testCompileForOneFile(rootDir)
 	// testLoadBaseFile(rootDir)
+	// viewLoadProgress(rootDir)
+}
+
+func viewLoadProgress(rootDir string) {
+	var loaded, failed = 0, 0
+	var opts = &golang.LoadOptions{OnEvent: func(event golang.LoadEvent) {
+		switch event.Kind {
+		case golang.LoadEventPackageLoaded:
+			loaded++
+			fmt.Printf("[%d/%d] %s:\t%s\n", event.Count, event.Total, event.Kind, event.PkgPath)
+		case golang.LoadEventTypeCheckFailed, golang.LoadEventDirSkipped:
+			failed++
+			fmt.Printf("[%d/%d] %s:\t%s\n\t-- %v\n", event.Count, event.Total, event.Kind, event.DirPath, event.Err)
+		}
+	}}
+	var begTime = time.Now()
+	_, _ = golang.NewDefaultProgram(rootDir, opts)
+	fmt.Printf("Taking %v seconds: %d loaded; %d failed.\n", int(time.Since(begTime).Seconds()), loaded, failed)
 }
 
 func testLoadBaseFile(rootDir string) {
@@ -70,6 +88,9 @@ func testLoadBaseFile(rootDir string) {
 	fmt.Printf("Total:\t+%d; -%d; (%v).\n", passNumber, noneNumber, percent(passNumber, noneNumber))
 }
 
+// walkOptions configures the directories skipped in walking, nil to skip vendor, testdata and dot.
+var walkOptions *golang.LoadOptions
+
 // findPackagesAndGoFiles return a map from directory to the go files included.
 func findPackagesAndGoFiles(rootDir string) map[string][]string {
 	var goFiles []string
@@ -77,6 +98,9 @@ func findPackagesAndGoFiles(rootDir string) map[string][]string {
 		if err != nil {
 			return err
 		}
+		if info.IsDir() && path != rootDir && walkOptions.SkipDir(info.Name()) {
+			return filepath.SkipDir
+		}
 		if !info.IsDir() && strings.HasSuffix(path, ".go") {
 			goFiles = append(goFiles, path)
 		}
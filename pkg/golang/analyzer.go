@@ -0,0 +1,107 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file defines Analyzer, which takes a Package as input and reports Diagnostic,
+// and implements the runner of analyzers over the packages of Program concurrently.
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Analyzer is a static analyzer which checks a Package and reports the findings as Diagnostic.
+//
+// Run is invoked concurrently on the different packages, thus the analyzer should not share the
+// mutable state among the runs without synchronization.
+type Analyzer interface {
+	Name() string                           // Name is the unique name of this analyzer
+	Run(pkg *Package) ([]Diagnostic, error) // Run checks the package and returns the findings
+}
+
+// Analyze runs each analyzer over each loaded package in the program, where the packages are taken
+// by a bounded pool of goroutines (GOMAXPROCS) and the analyzers are run in order on each package.
+// It returns the diagnostics in order of package paths and analyzers, along with the errors of all
+// failed runs joined, and the diagnostics of failed runs are kept as well. The diagnostics on lines
+// annotated by the nolint directives are dropped, as Package.FilterSuppressed.
+func (prog *Program) Analyze(analyzers []Analyzer) ([]Diagnostic, error) {
+	return prog.analyze(analyzers, runtime.GOMAXPROCS(0))
+}
+
+// analyze runs the analyzers over the loaded packages with at most the given number of goroutines.
+func (prog *Program) analyze(analyzers []Analyzer, concurrency int) ([]Diagnostic, error) {
+	// 1. collect the loaded packages in order of paths
+	if prog == nil {
+		return nil, fmt.Errorf("nil program is used")
+	}
+	var pkgs []*Package
+	for _, pkg := range prog.AllPackages() {
+		if pkg.IsLoaded() {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].pkgPath < pkgs[j].pkgPath })
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// 2. run analyzers on the packages in the bounded pool
+	var diagnostics = make([][]Diagnostic, len(pkgs))
+	var errs = make([][]error, len(pkgs))
+	var indices = make(chan int)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < len(pkgs); worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range indices {
+				diagnostics[index], errs[index] = runAnalyzers(analyzers, pkgs[index])
+			}
+		}()
+	}
+	for index := range pkgs {
+		indices <- index
+	}
+	close(indices)
+	waitGroup.Wait()
+
+	// 3. aggregate the diagnostics and errors in order
+	var allDiagnostics []Diagnostic
+	var allErrors []error
+	for index := range pkgs {
+		allDiagnostics = append(allDiagnostics, diagnostics[index]...)
+		allErrors = append(allErrors, errs[index]...)
+	}
+	return allDiagnostics, errors.Join(allErrors...)
+}
+
+// runAnalyzers runs the analyzers on the package in order, and recovers the panics as errors. The
+// diagnostics suppressed by the nolint directives in package are filtered out.
+func runAnalyzers(analyzers []Analyzer, pkg *Package) ([]Diagnostic, []error) {
+	var diagnostics []Diagnostic
+	var errs []error
+	for _, analyzer := range analyzers {
+		if analyzer == nil {
+			continue
+		}
+		pkgDiagnostics, err := runAnalyzer(analyzer, pkg)
+		diagnostics = append(diagnostics, pkgDiagnostics...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s on %s: %w", analyzer.Name(), pkg.pkgPath, err))
+		}
+	}
+	return pkg.FilterSuppressed(diagnostics), errs
+}
+
+// runAnalyzer runs the analyzer on the package, of which panic is recovered as the error.
+func runAnalyzer(analyzer Analyzer, pkg *Package) (diagnostics []Diagnostic, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	return analyzer.Run(pkg)
+}
@@ -0,0 +1,78 @@
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// funcAnalyzer flags every function declaration in the package.
+type funcAnalyzer struct{}
+
+func (funcAnalyzer) Name() string { return "funcs" }
+
+func (funcAnalyzer) Run(pkg *Package) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, funcDecl := range srcFile.Functions() {
+			diagnostics = append(diagnostics, Diagnostic{
+				Pos:      funcDecl.Pos(),
+				Category: "funcs",
+				Message:  "function " + funcDecl.Name.Name,
+			})
+		}
+	}
+	return diagnostics, nil
+}
+
+// failAnalyzer fails on the package b, either by error or panic.
+type failAnalyzer struct{ panics bool }
+
+func (analyzer failAnalyzer) Name() string { return fmt.Sprintf("fail(panics=%v)", analyzer.panics) }
+
+func (analyzer failAnalyzer) Run(pkg *Package) ([]Diagnostic, error) {
+	if pkg.PkgName() != "b" {
+		return nil, nil
+	}
+	if analyzer.panics {
+		panic("crafted panic in analyzer")
+	}
+	return []Diagnostic{{Message: "kept"}}, errors.New("crafted error in analyzer")
+}
+
+func TestProgramAnalyze(t *testing.T) {
+	var files = map[string]string{"b/b.go": "package b\n\nfunc B1() {}\n\nfunc B2() {}\n"}
+	for i := 0; i < 8; i++ {
+		files[fmt.Sprintf("p%d/p.go", i)] = fmt.Sprintf("package p%d\n\nfunc F() {}\n\ntype T struct{}\n\nfunc (T) M() {}\n", i)
+	}
+	prog, err := Load(writeModule(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, concurrency := range []int{0, 1, 3, 16} {
+		diagnostics, err := prog.analyze([]Analyzer{funcAnalyzer{}}, concurrency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diagnostics) != 2+8*2 {
+			t.Errorf("%d diagnostics with concurrency %d, want %d", len(diagnostics), concurrency, 2+8*2)
+		}
+		if diagnostics[0].Message != "function B1" || diagnostics[1].Message != "function B2" {
+			t.Errorf("diagnostics are not in order of packages: %v", diagnostics[:2])
+		}
+	}
+
+	diagnostics, err := prog.Analyze([]Analyzer{funcAnalyzer{}, failAnalyzer{}, nil, failAnalyzer{panics: true}})
+	if len(diagnostics) != 2+8*2+1 {
+		t.Errorf("%d diagnostics, want those of failed runs kept", len(diagnostics))
+	}
+	if err == nil || !strings.Contains(err.Error(), "crafted error in analyzer") ||
+		!strings.Contains(err.Error(), "panic: crafted panic in analyzer") {
+		t.Errorf("error = %v, want the error and panic of failed runs", err)
+	}
+	var nilProg *Program
+	if _, err := nilProg.Analyze(nil); err == nil {
+		t.Errorf("Analyze(nil program) = nil, want error")
+	}
+}
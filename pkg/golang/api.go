@@ -1,6 +1,7 @@
 package golang
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/importer"
@@ -8,16 +9,18 @@ import (
 	"go/token"
 	"go/types"
 	"golang.org/x/tools/go/packages"
-	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
 	GoFileSuffix  = ".go"      // GoFileSuffix defines the suffix of go source files
 	PackagePrefix = "package"  // PackagePrefix is the prefix of code line in package declaration
 	GoModFileName = "go.mod"   // GoModFileName is the name of `go.mod` file to find module name
+	GoSumFileName = "go.sum"   // GoSumFileName is the name of `go.sum` file with module hashes
 	GoModIndirect = "indirect" // GoModIndirect is the 'indirect' flag to specify dependency one
 	ModulePrefix  = "module "  // ModulePrefix is the prefix of code line in `go.mod` with module
 	VersionPrefix = "go "      // VersionPrefix is the prefix of code line in go.mod with version
@@ -27,23 +30,57 @@ const (
 	SpaceChar = " "  // SpaceChar is a space ' '
 )
 
+// The sentinel errors wrapped by the loaders, such that callers could distinguish the failures by
+// errors.Is, e.g. to treat a directory outside any module differently from a parse error.
+var (
+	ErrNoGoMod    = errors.New("no go.mod is found") // ErrNoGoMod is the failure to find go.mod
+	ErrNotGoFile  = errors.New("not go file")        // ErrNotGoFile is a path not of go source
+	ErrEmptyFile  = errors.New("empty file")         // ErrEmptyFile is a file without content
+	ErrNoPackages = errors.New("no go files")        // ErrNoPackages is a directory without go files
+)
+
 func LoadBaseFile(srcFile string) (*SrcFile, error) {
 	// 1. validate the input and get its source file directory
 	if _, fileErr := os.Stat(srcFile); os.IsNotExist(fileErr) {
 		return nil, fileErr
 	} else if !strings.HasSuffix(srcFile, GoFileSuffix) {
-		return nil, fmt.Errorf("not go file: %s", srcFile)
+		return nil, fmt.Errorf("%w: %s", ErrNotGoFile, srcFile)
 	}
-	var srcPath, _ = filepath.Abs(srcFile)
-	var dirPath = filepath.Clean(filepath.Dir(srcPath))
 
-	// 2. read the source code and parse the syntax tree
-	var bytes, readErr = os.ReadFile(srcPath)
+	// 2. read the source code and parse it as base file
+	var bytes, readErr = os.ReadFile(srcFile)
 	if readErr != nil {
 		return nil, readErr
 	}
+	return LoadBaseFileWithContent(srcFile, bytes)
+}
+
+// LoadBaseFileWithContent parses and type-checks the content of source file held in memory (e.g. an
+// unsaved buffer of editor) as LoadBaseFile does, which is not read from the disk again.
+func LoadBaseFileWithContent(srcFile string, content []byte) (*SrcFile, error) {
+	if !strings.HasSuffix(srcFile, GoFileSuffix) {
+		return nil, fmt.Errorf("%w: %s", ErrNotGoFile, srcFile)
+	}
+	var srcPath, _ = filepath.Abs(srcFile)
+	var dirPath = filepath.Clean(filepath.Dir(srcPath))
+	return loadBaseSource(srcPath, dirPath, content)
+}
+
+// LoadBaseSource parses and type-checks the source code held in memory (e.g. a blob from VCS), of
+// which name is used as the virtual path. The Code of output SrcFile is the src and Path is name.
+func LoadBaseSource(name string, src []byte) (*SrcFile, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf("no name for source")
+	}
+	return loadBaseSource(name, filepath.Clean(filepath.Dir(name)), src)
+}
+
+// loadBaseSource parses the syntax tree of source code in bytes, and performs the type checking on
+// it as the only file in package of dirPath, then returns the SrcFile of srcPath for output.
+func loadBaseSource(srcPath, dirPath string, bytes []byte) (*SrcFile, error) {
+	// 1. parse the syntax tree from the source code
 	var fileSet = token.NewFileSet()
-	syntax, parseErr := parser.ParseFile(fileSet, srcPath, nil, parser.ParseComments)
+	syntax, parseErr := parser.ParseFile(fileSet, srcPath, bytes, parser.ParseComments)
 	if parseErr != nil {
 		return nil, parseErr
 	}
@@ -51,7 +88,7 @@ func LoadBaseFile(srcFile string) (*SrcFile, error) {
 		return nil, fmt.Errorf("cannot parse: %s", srcPath)
 	}
 
-	// 3. perform the types checking on the syntax tree
+	// 2. perform the types checking on the syntax tree
 	typeConfig := &types.Config{
 		Context:                  types.NewContext(),
 		IgnoreFuncBodies:         false,
@@ -72,16 +109,27 @@ func LoadBaseFile(srcFile string) (*SrcFile, error) {
 		InitOrder:  nil,
 	}
 
-	// 4. generate the types.Package
-	typePkg, typeErr := typeConfig.Check(dirPath, fileSet, []*ast.File{syntax}, info)
+	// 3. generate the types.Package
+	typePkg, typeErr := checkTypes(typeConfig, dirPath, fileSet, []*ast.File{syntax}, info)
 	if typeErr != nil {
 		// ignore the type error and return a source file with incomplete types
 	} else if typePkg == nil {
 		return nil, fmt.Errorf("cannot get the types.Package: %s", dirPath)
 	}
 
-	// 5. construct the *Package and the only *SrcFile for output
+	// 4. construct the *Package and the only *SrcFile for output
 	pkg := newPackage(nil, syntax.Name.Name, dirPath, dirPath)
+	pkg.fileSet = fileSet
+	pkg.typePkg = typePkg
+	pkg.typInfo = info
+	pkg.loadInfo = &LoadInfo{
+		LoadTime:    time.Now(),
+		LoadedFiles: []string{srcPath},
+		IllTyped:    typeErr != nil,
+	}
+	if typeErr != nil {
+		pkg.loadInfo.TypeErrors = []error{typeErr}
+	}
 	file := pkg.newSrcFile(srcPath)
 	fileErr := file.update(string(bytes), syntax, nil)
 	if fileErr != nil {
@@ -92,6 +140,15 @@ func LoadBaseFile(srcFile string) (*SrcFile, error) {
 
 // LoadOneFile parses the AST of source file and its corresponding package info.
 func LoadOneFile(srcFile string) (*ast.File, *packages.Package, error) {
+	return LoadOneFileWithOptions(srcFile, nil, nil)
+}
+
+// LoadOneFileWithOptions parses the AST of source file and its corresponding package info as
+// LoadOneFile does, where the build tags, target platform and overlay in options (nil for default)
+// and the additional build flags (e.g. "-mod=vendor") are passed to the go/packages loader, such
+// that the files guarded by the build tags (e.g. `//go:build integration`) could be loaded.
+func LoadOneFileWithOptions(srcFile string, opts *LoadOptions,
+	buildFlags []string) (*ast.File, *packages.Package, error) {
 	// 1. validate the input file path
 	if _, fileErr := os.Stat(srcFile); os.IsNotExist(fileErr) {
 		return nil, nil, fmt.Errorf("undef file: %s", srcFile)
@@ -108,9 +165,17 @@ func LoadOneFile(srcFile string) (*ast.File, *packages.Package, error) {
 		Mode: packages.NeedName | packages.NeedFiles |
 			packages.NeedTypes | packages.NeedTypesInfo |
 			packages.NeedSyntax,
-		Dir:   srcDir,
-		Fset:  fileSet,
-		Tests: true,
+		Dir:        srcDir,
+		Fset:       fileSet,
+		Tests:      true,
+		BuildFlags: append([]string{}, buildFlags...),
+	}
+	if opts != nil {
+		if len(opts.BuildTags) > 0 {
+			loadConf.BuildFlags = append(loadConf.BuildFlags, "-tags="+strings.Join(opts.BuildTags, ","))
+		}
+		loadConf.Env = append(os.Environ(), "GOOS="+opts.goos(), "GOARCH="+opts.goarch())
+		loadConf.Overlay = opts.Overlay
 	}
 	loadPkgs, loadErr := packages.Load(loadConf, srcDir)
 	if loadErr != nil {
@@ -169,28 +234,11 @@ func LoadOnePkg(srcDir string) (*packages.Package, error) {
 }
 
 // LoadAllPkg will parse the AST of all source files under the directory and
-// load the type & package information.
+// load the type & package information. The packages (`./...` under srcDir) are
+// returned once per PkgPath in sorted order, preferring the test variants that
+// include the in-package test files.
 func LoadAllPkg(srcDir string) ([]*packages.Package, error) {
-	// 1. collect the set of directories with source files
-	var pkgToSrcFiles = make(map[string][]string)
-	_ = filepath.Walk(srcDir, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(path, ".go") {
-			dir := filepath.Dir(path)
-			pkgToSrcFiles[dir] = append(pkgToSrcFiles[dir], path)
-		}
-		return nil
-	})
-	var pkgDirs []string
-	for pkgPath, srcFiles := range pkgToSrcFiles {
-		if len(srcFiles) > 0 {
-			pkgDirs = append(pkgDirs, pkgPath)
-		}
-	}
-
-	// 2. initialize the config and parse AST packages
+	// 1. initialize the config and parse AST packages
 	fileSet := token.NewFileSet()
 	loadConf := &packages.Config{
 		Mode: packages.NeedName | packages.NeedFiles |
@@ -200,17 +248,181 @@ func LoadAllPkg(srcDir string) ([]*packages.Package, error) {
 		Fset:  fileSet,
 		Tests: true,
 	}
-	loadPkgs, loadErr := packages.Load(loadConf, srcDir)
+	loadPkgs, loadErr := packages.Load(loadConf, "./...")
 	if loadErr != nil {
 		return nil, loadErr
 	}
 
-	// 3. collect the output packages and return them if any
-	var resultPkgs []*packages.Package
+	// 2. deduplicate the packages with their test variants
+	var pkgMap = make(map[string]*packages.Package)
 	for _, loadPkg := range loadPkgs {
-		if loadPkg != nil {
-			resultPkgs = append(resultPkgs, loadPkg)
+		if loadPkg == nil {
+			continue
+		}
+		if prevPkg, ok := pkgMap[loadPkg.PkgPath]; !ok || len(loadPkg.GoFiles) > len(prevPkg.GoFiles) {
+			pkgMap[loadPkg.PkgPath] = loadPkg // the test variant includes in-package test files
 		}
 	}
+
+	// 3. collect the output packages in order of paths
+	var resultPkgs = make([]*packages.Package, 0, len(pkgMap))
+	for _, loadPkg := range pkgMap {
+		resultPkgs = append(resultPkgs, loadPkg)
+	}
+	sort.Slice(resultPkgs, func(i, j int) bool { return resultPkgs[i].PkgPath < resultPkgs[j].PkgPath })
 	return resultPkgs, nil
 }
+
+// NewDefaultProgram creates the Program of module containing rootDir (where a go.mod is found in it
+// or any of its parents), and loads the packages in rootDir and its recursive sub-directories with
+// the options (nil for the current platform), where the directories failed to load are skipped.
+// The program is returned even if some directories are skipped, along with their DirError joined,
+// such that callers can tell the directories failed from those without packages.
+func NewDefaultProgram(rootDir string, opts *LoadOptions) (*Program, error) {
+	// 1. validate the input directory
+	rootDirPath, _ := filepath.Abs(rootDir)
+	fileInfo, err := os.Stat(rootDirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !fileInfo.IsDir() {
+		return nil, fmt.Errorf("not directory: %s", rootDirPath)
+	}
+
+	// 2. create the program of module and load packages
+	program, modErr := initProgram(rootDirPath)
+	if modErr != nil {
+		return nil, modErr
+	}
+	if program == nil || program.module == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoGoMod, rootDir)
+	}
+	_, dirErrs := program.loadAllDirectories(rootDirPath, opts)
+	return program, errors.Join(dirErrs...)
+}
+
+// FindPackagePath returns the import path of package in the directory, which is inferred from the
+// module declared by the nearest go.mod in the directory or its parents, e.g. "example.com/m/a/b"
+// for the directory "a/b" under the module root. It returns the error wrapping ErrNoGoMod if no
+// go.mod is found, or error if the directory doesn't exist or is out of the module.
+func FindPackagePath(dirPath string) (string, error) {
+	// 1. find the module of nearest go.mod above
+	dirPath, _ = filepath.Abs(dirPath)
+	if fileInfo, err := os.Stat(dirPath); err != nil {
+		return "", err
+	} else if !fileInfo.IsDir() {
+		return "", fmt.Errorf("not directory: %s", dirPath)
+	}
+	goModFile, modErr := goModFileOf(dirPath)
+	if modErr != nil {
+		return "", modErr
+	}
+	module, modErr := newModule(goModFile)
+	if modErr != nil {
+		return "", modErr
+	}
+
+	// 2. infer the package path in the module
+	relPath, relErr := filepath.Rel(module.RootPath, dirPath)
+	if relErr != nil || strings.HasPrefix(relPath, "..") {
+		return "", fmt.Errorf("out of module %s: %s", module.ModuleName, dirPath)
+	}
+	pkgPath, _, _, err := inferGoPkgInfo(module, dirPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(pkgPath), nil
+}
+
+// Load creates the Program of module containing rootDir and loads the packages in rootDir and its
+// recursive sub-directories as NewDefaultProgram, with the LoadOptions configured by the options.
+func Load(rootDir string, options ...Option) (*Program, error) {
+	return NewDefaultProgram(rootDir, newLoadOptions(options...))
+}
+
+// NewVirtualPackage creates a package from the in-memory source files, which maps from the virtual
+// file names to their code, such that analyzers can be tested without creating files on the disk.
+func NewVirtualPackage(pkgName, pkgPath string, files map[string]string) (*Package, error) {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pkg := newPackage(nil, pkgName, pkgPath, "")
+	for _, name := range names {
+		if _, err := pkg.AddVirtualFile(name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	return pkg, nil
+}
+
+// AddVirtualFile parses the content as source code of file with given name, adds it to this package
+// and re-runs the type checking on the package (with the options it was loaded with). The name is
+// used as the file name in FileSet, such that positions of the virtual file could be printed
+// meaningfully.
+func (pkg *Package) AddVirtualFile(name string, content string) (*SrcFile, error) {
+	// 1. parse the syntax tree from the content
+	if pkg == nil {
+		return nil, fmt.Errorf("nil package is used")
+	}
+	if pkg.fileSet == nil {
+		pkg.fileSet = token.NewFileSet()
+	}
+	syntax, parseErr := parser.ParseFile(pkg.fileSet, name, content, parser.ParseComments)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if syntax == nil {
+		return nil, fmt.Errorf("cannot parse: %s", name)
+	}
+	file := pkg.newSrcFile(name)
+	if fileErr := file.update(content, syntax, nil); fileErr != nil {
+		return nil, fileErr
+	}
+
+	// 2. collect the syntax trees of all files in package
+	var paths []string
+	for path, srcFile := range pkg.srcFiles {
+		if srcFile != nil && srcFile.syntax != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	var astFiles []*ast.File
+	var imports = make(map[string]bool)
+	pkg.imports = nil
+	for _, path := range paths {
+		syntax := pkg.srcFiles[path].syntax
+		astFiles = append(astFiles, syntax)
+		for _, importSpec := range syntax.Imports {
+			if importSpec == nil || importSpec.Path == nil {
+				continue
+			}
+			importPath := strings.Trim(importSpec.Path.Value, "\"")
+			if len(importPath) > 0 && !imports[importPath] {
+				imports[importPath] = true
+				pkg.imports = append(pkg.imports, importPath)
+			}
+		}
+	}
+
+	// 3. perform the type checking on the package
+	typeConf := newDefaultTypeConfig(pkg.program, pkg.options)
+	typeInfo := newDefaultTypeInfo(pkg.options)
+	typePkg, typeErr := checkTypes(typeConf, pkg.pkgPath, pkg.fileSet, astFiles, typeInfo)
+	pkg.typePkg = typePkg
+	pkg.typInfo = typeInfo
+	pkg.typSize = &typeConf.Sizes
+	pkg.importer = typeConf.Importer
+	pkg.loadInfo = &LoadInfo{
+		LoadTime:    time.Now(),
+		LoadedFiles: paths,
+		IllTyped:    typeErr != nil,
+	}
+	if typeErr != nil {
+		pkg.loadInfo.TypeErrors = []error{typeErr}
+	}
+	return file, nil
+}
@@ -0,0 +1,292 @@
+package golang
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewVirtualPackage(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nimport \"strings\"\n\nfunc A(s string) string { return strings.ToUpper(B(s)) }\n",
+		"b.go": "package p\n\nfunc B(s string) string { return s + s }\n",
+	})
+	if pkg.PkgName() != "p" || pkg.PkgPath() != "example.com/p" {
+		t.Fatalf("package = %s %s, want p example.com/p", pkg.PkgName(), pkg.PkgPath())
+	}
+	if got := baseNamesOf(pkg.GoFiles()); !reflect.DeepEqual(got, []string{"a.go", "b.go"}) {
+		t.Errorf("GoFiles = %v, want [a.go b.go]", got)
+	}
+	if got := pkg.Imports(); !reflect.DeepEqual(got, []string{"strings"}) {
+		t.Errorf("Imports = %v, want [strings]", got)
+	}
+	if pkg.TypePkg() == nil || pkg.TypePkg().Scope().Lookup("A") == nil || pkg.TypePkg().Scope().Lookup("B") == nil {
+		t.Fatalf("A and B are not type-checked in %v", pkg.TypePkg())
+	}
+
+	// positions of the virtual files are printed with their names
+	srcFile := pkg.SrcFile("b.go")
+	if srcFile.Code() != "package p\n\nfunc B(s string) string { return s + s }\n" {
+		t.Errorf("Code = %q", srcFile.Code())
+	}
+	position := pkg.FileSet().Position(srcFile.Syntax().Decls[0].Pos())
+	if position.Filename != "b.go" || position.Line != 3 {
+		t.Errorf("position = %v, want b.go:3", position)
+	}
+
+	// the added file is checked along with the existing ones
+	if _, err := pkg.AddVirtualFile("c.go", "package p\n\nvar C = A(\"c\")\n"); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.LoadInfo().HasErrors() || pkg.TypePkg().Scope().Lookup("C") == nil {
+		t.Errorf("C is not type-checked: %v", pkg.LoadInfo().AllErrors())
+	}
+	if _, err := pkg.AddVirtualFile("d.go", "package p\n\nvar D = undefined\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !pkg.LoadInfo().IllTyped {
+		t.Errorf("IllTyped = false, want true after adding an ill-typed file")
+	}
+	if _, err := pkg.AddVirtualFile("e.go", "package p\n\nfunc {"); err == nil {
+		t.Errorf("AddVirtualFile(syntax error) = nil, want error")
+	}
+	if _, err := NewVirtualPackage("p", "example.com/p", map[string]string{"a.go": "package"}); err == nil {
+		t.Errorf("NewVirtualPackage(syntax error) = nil, want error")
+	}
+}
+
+func TestLoadBaseFileWithContent(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{"p/p.go": "package p\n\nconst OnDisk = 1\n"})
+	var path = filepath.Join(rootDir, "p", "p.go")
+	srcFile, err := LoadBaseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Path() != path || srcFile.Package().Lookup("OnDisk") == nil {
+		t.Errorf("LoadBaseFile(%s) = %s without OnDisk", path, srcFile.Path())
+	}
+
+	const content = "package p\n\nconst InMemory = 2\n"
+	srcFile, err = LoadBaseFileWithContent(path, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Code() != content || srcFile.Package().Lookup("InMemory") == nil ||
+		srcFile.Package().Lookup("OnDisk") != nil {
+		t.Errorf("LoadBaseFileWithContent = %q, want the content rather than the file on disk", srcFile.Code())
+	}
+	if srcFile.Package().DirPath() != filepath.Dir(path) {
+		t.Errorf("DirPath = %s, want %s", srcFile.Package().DirPath(), filepath.Dir(path))
+	}
+	if _, err := LoadBaseFileWithContent(filepath.Join(rootDir, "p", "missing.go"), []byte(content)); err != nil {
+		t.Errorf("LoadBaseFileWithContent(missing file) = %v, want nil without reading the disk", err)
+	}
+	if _, err := LoadBaseFile(filepath.Join(rootDir, "p", "missing.go")); err == nil {
+		t.Errorf("LoadBaseFile(missing file) = nil, want error")
+	}
+}
+
+func TestLoadBaseSource(t *testing.T) {
+	var src = []byte("package blob\n\nimport \"fmt\"\n\nfunc Hello() string { return fmt.Sprint(\"hello\") }\n")
+	srcFile, err := LoadBaseSource("blob/hello.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Path() != "blob/hello.go" || srcFile.Code() != string(src) {
+		t.Errorf("Path = %q and Code = %q, want the name and source", srcFile.Path(), srcFile.Code())
+	}
+	if srcFile.Syntax() == nil || srcFile.Syntax().Name.Name != "blob" || len(srcFile.Syntax().Decls) != 2 {
+		t.Fatalf("syntax tree is not populated: %#v", srcFile.Syntax())
+	}
+	pkg := srcFile.Package()
+	if pkg.PkgName() != "blob" || pkg.LoadInfo().HasErrors() {
+		t.Errorf("package %q is loaded with %v", pkg.PkgName(), pkg.LoadInfo().AllErrors())
+	}
+	if pkg.TypePkg() == nil || pkg.TypePkg().Scope().Lookup("Hello") == nil {
+		t.Errorf("Hello is not type-checked in %v", pkg.TypePkg())
+	}
+
+	if _, err := LoadBaseSource("", src); err == nil {
+		t.Errorf("LoadBaseSource(no name) = nil, want error")
+	}
+	if _, err := LoadBaseSource("bad.go", []byte("package")); err == nil {
+		t.Errorf("LoadBaseSource(syntax error) = nil, want error")
+	}
+}
+
+func TestNewDefaultProgram(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":     "package a\n\nimport \"example.com/m/sub/b\"\n\nvar A = b.B\n",
+		"sub/b/b.go": "package b\n\nconst B = 1\n",
+		"c/c.go":     "package c\n\nfunc {\n",
+		"docs/x.md":  "not a package\n",
+	})
+	prog, err := NewDefaultProgram(rootDir, nil)
+	if prog == nil {
+		t.Fatalf("NewDefaultProgram(%s) = nil, %v", rootDir, err)
+	}
+	var dirErr *DirError
+	if !errors.As(err, &dirErr) || dirErr.DirPath != filepath.Join(rootDir, "c") {
+		t.Errorf("error = %v, want the DirError of c", err)
+	}
+	if prog.Module().ModuleName != testModulePath || prog.Module().RootPath != rootDir {
+		t.Errorf("module = %s in %s, want %s in %s", prog.Module().ModuleName, prog.Module().RootPath,
+			testModulePath, rootDir)
+	}
+	for _, pkgPath := range []string{testModulePath + "/a", testModulePath + "/sub/b"} {
+		if pkg := mustPackage(t, prog, pkgPath); pkg.LoadInfo().HasErrors() || pkg.TypePkg() == nil {
+			t.Errorf("%s is loaded with %v", pkgPath, pkg.LoadInfo().AllErrors())
+		}
+	}
+	if pkg := prog.Package(testModulePath + "/docs"); pkg != nil {
+		t.Errorf("directory without source files is loaded as %v", pkg)
+	}
+
+	if _, err := NewDefaultProgram(filepath.Join(rootDir, "missing"), nil); err == nil {
+		t.Errorf("NewDefaultProgram(missing) = nil, want error")
+	}
+	if _, err := NewDefaultProgram(filepath.Join(rootDir, GoModFileName), nil); err == nil {
+		t.Errorf("NewDefaultProgram(file) = nil, want error")
+	}
+}
+
+func TestFindPackagePath(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/b/b.go":          "package b\n",
+		"nested/go.mod":     "module example.com/nested\n",
+		"nested/sub/sub.go": "package sub\n",
+	})
+	noModDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		dirPath string
+		pkgPath string
+	}{
+		{rootDir, testModulePath},
+		{filepath.Join(rootDir, "a", "b"), testModulePath + "/a/b"},
+		{filepath.Join(rootDir, "nested", "sub"), "example.com/nested/sub"},
+	} {
+		if pkgPath, err := FindPackagePath(test.dirPath); err != nil || pkgPath != test.pkgPath {
+			t.Errorf("FindPackagePath(%s) = %q, %v, want %q", test.dirPath, pkgPath, err, test.pkgPath)
+		}
+	}
+	for _, dirPath := range []string{noModDir, filepath.Join(rootDir, "missing"), filepath.Join(rootDir, "a", "b", "b.go")} {
+		if pkgPath, err := FindPackagePath(dirPath); err == nil {
+			t.Errorf("FindPackagePath(%s) = %q, want error", dirPath, pkgPath)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":       "package a\n",
+		"e/e.go":       "package e\n",
+		"docs/a.txt":   "not go\n",
+		"empty/go.mod": "",
+	})
+	noModDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loadDir = func(dirPath string) error {
+		_, err := loadGoDirectoryByFree(dirPath, nil)
+		return err
+	}
+	for _, test := range []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"loadGoDirectoryByFree without go.mod", loadDir(noModDir), ErrNoGoMod},
+		{"FindPackagePath without go.mod", func() error { _, err := FindPackagePath(noModDir); return err }(), ErrNoGoMod},
+		{"Load without go.mod", func() error { _, err := Load(noModDir); return err }(), ErrNoGoMod},
+		{"LoadBaseFile of text", func() error {
+			_, err := LoadBaseFile(filepath.Join(rootDir, "docs", "a.txt"))
+			return err
+		}(), ErrNotGoFile},
+		{"LoadBaseFileWithContent of text", func() error { _, err := LoadBaseFileWithContent("a.txt", nil); return err }(), ErrNotGoFile},
+		{"loadSourceFileByFree of directory", func() error {
+			_, err := loadSourceFileByFree(filepath.Join(rootDir, "a"), nil)
+			return err
+		}(), ErrNotGoFile},
+		{"newModule of empty go.mod", func() error {
+			_, err := newModule(filepath.Join(rootDir, "empty", GoModFileName))
+			return err
+		}(), ErrEmptyFile},
+		{"loadSourceFileByFree of emptied file", func() error {
+			var path = filepath.Join(rootDir, "e", "e.go")
+			_, err := loadSourceFileByFree(path, &LoadOptions{Overlay: map[string][]byte{path: {}}})
+			return err
+		}(), ErrEmptyFile},
+		{"loadGoDirectoryByFree without go files", loadDir(filepath.Join(rootDir, "docs")), ErrNoPackages},
+	} {
+		if !errors.Is(test.err, test.target) {
+			t.Errorf("%s: error = %v, want %v", test.name, test.err, test.target)
+		}
+	}
+}
+
+// skipIfPackagesPanic skips the test if go/packages panics in loading, e.g. when the export data of
+// the installed toolchain is newer than the version golang.org/x/tools supports.
+func skipIfPackagesPanic(t *testing.T) {
+	if e := recover(); e != nil {
+		t.Skipf("go/packages is unsupported by the toolchain: %v", e)
+	}
+}
+
+func TestLoadOneFileWithOptions(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go":           "package p\n\nconst Default = true\n",
+		"p/integration.go": "//go:build integration\n\npackage p\n\nconst Integration = Default\n",
+	})
+	defer skipIfPackagesPanic(t)
+	var path = filepath.Join(rootDir, "p", "integration.go")
+	if syntax, _, err := LoadOneFile(path); err == nil {
+		t.Errorf("LoadOneFile without tags = %v, want error of the excluded file", syntax.Name)
+	}
+	for _, test := range []struct {
+		opts       *LoadOptions
+		buildFlags []string
+	}{
+		{&LoadOptions{BuildTags: []string{"integration"}}, nil},
+		{nil, []string{"-tags=integration"}},
+	} {
+		syntax, loadPkg, err := LoadOneFileWithOptions(path, test.opts, test.buildFlags)
+		if err != nil {
+			t.Fatalf("LoadOneFileWithOptions(%+v, %v): %v", test.opts, test.buildFlags, err)
+		}
+		if syntax == nil || loadPkg.Types == nil || loadPkg.Types.Scope().Lookup("Integration") == nil {
+			t.Errorf("LoadOneFileWithOptions(%+v, %v): Integration is not type-checked", test.opts, test.buildFlags)
+		}
+		if len(loadPkg.Errors) > 0 {
+			t.Errorf("LoadOneFileWithOptions(%+v, %v): errors = %v", test.opts, test.buildFlags, loadPkg.Errors)
+		}
+	}
+}
+
+func TestLoadAllPkg(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":     "package a\n\nimport \"example.com/m/a/b\"\n\nconst A = b.B\n",
+		"a/b/b.go":   "package b\n\nconst B = 2\n",
+		"a/b/c/c.go": "package c\n\nconst C = 3\n",
+		"d/d.go":     "package d\n\nconst D = 4\n",
+	})
+	defer skipIfPackagesPanic(t)
+	loadPkgs, err := LoadAllPkg(filepath.Join(rootDir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkgPaths []string
+	for _, loadPkg := range loadPkgs {
+		pkgPaths = append(pkgPaths, loadPkg.PkgPath)
+		if loadPkg.Types == nil || len(loadPkg.Errors) > 0 {
+			t.Errorf("%s is loaded with %v", loadPkg.PkgPath, loadPkg.Errors)
+		}
+	}
+	if want := []string{testModulePath + "/a", testModulePath + "/a/b", testModulePath + "/a/b/c"}; !reflect.DeepEqual(pkgPaths, want) {
+		t.Errorf("paths of LoadAllPkg = %v, want %v", pkgPaths, want)
+	}
+}
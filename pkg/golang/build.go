@@ -0,0 +1,354 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the evaluation of build constraints (including `//go:build`
+// and the legacy `// +build` lines) to decide which source files are included in loading.
+package golang
+
+import (
+	"fmt"
+	"go/build"
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH recognized by the go command, which are
+// implicitly satisfied as build tags on the target platform (see syslist.go of go/build).
+var (
+	knownOS = strings.Fields("aix android darwin dragonfly freebsd hurd illumos ios js linux nacl " +
+		"netbsd openbsd plan9 solaris wasip1 windows zos")
+	knownArch = strings.Fields("386 amd64 amd64p32 arm armbe arm64 arm64be loong64 mips mipsle " +
+		"mips64 mips64le mips64p32 mips64p32le ppc ppc64 ppc64le riscv riscv64 s390 s390x sparc " +
+		"sparc64 wasm")
+)
+
+// knownUnixOS are the values of GOOS satisfying the "unix" build tag (see syslist.go of go/build).
+var knownUnixOS = strings.Fields("aix android darwin dragonfly freebsd hurd illumos ios linux netbsd " +
+	"openbsd solaris")
+
+// newBuildContext returns the build.Context used to evaluate the build constraints of source files
+// on the target platform specified in the options.
+func newBuildContext(opts *LoadOptions) *build.Context {
+	buildContext := build.Default
+	buildContext.GOOS = opts.goos()
+	buildContext.GOARCH = opts.goarch()
+	if opts != nil && len(opts.BuildTags) > 0 {
+		buildContext.BuildTags = append([]string(nil), opts.BuildTags...)
+	}
+	if buildContext.GOOS != build.Default.GOOS || buildContext.GOARCH != build.Default.GOARCH {
+		buildContext.CgoEnabled = false // cgo is disabled in cross-compiling by default
+	}
+	return &buildContext
+}
+
+// matchTag checks whether the build tag is satisfied by the build context as go/build does, where
+// the GOOS, GOARCH, compiler, cgo, release and custom tags are satisfied, along with the implied
+// ones, e.g. "unix" on Linux, and "linux" on Android.
+func matchTag(buildContext *build.Context, tag string) bool {
+	switch {
+	case tag == buildContext.GOOS || tag == buildContext.GOARCH || tag == buildContext.Compiler:
+		return true
+	case tag == "unix":
+		return contains(knownUnixOS, buildContext.GOOS)
+	case tag == "cgo":
+		return buildContext.CgoEnabled
+	case tag == "linux":
+		return buildContext.GOOS == "android"
+	case tag == "solaris":
+		return buildContext.GOOS == "illumos"
+	case tag == "darwin":
+		return buildContext.GOOS == "ios"
+	}
+	return contains(buildContext.BuildTags, tag) || contains(buildContext.ToolTags, tag) ||
+		contains(buildContext.ReleaseTags, tag)
+}
+
+// matchFileName checks whether the GOOS and GOARCH implied by the name of source file (e.g. the
+// suffixes `_linux` or `_windows_amd64`) are satisfied, where the files named with `_` or `.` as
+// prefix are always ignored as go/build does.
+func matchFileName(buildContext *build.Context, srcPath string) bool {
+	var name = filepath.Base(srcPath)
+	if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+		return false
+	}
+	for _, tag := range fileNamePlatform(srcPath) {
+		if !matchTag(buildContext, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerConstraintOf returns the build constraint expression of source code, which is parsed from
+// the `//go:build` line (preferred) or the conjunction of legacy `// +build` lines in the header, i.e.
+// the comments and blank lines before the first line of code. It returns nil if there is none.
+func headerConstraintOf(src []byte) (constraint.Expr, error) {
+	var goBuild, plusBuild constraint.Expr
+	var inBlockComment bool
+	for _, line := range strings.Split(string(src), NewLine) {
+		line = strings.TrimSpace(line)
+		if inBlockComment {
+			if index := strings.Index(line, "*/"); index >= 0 {
+				inBlockComment, line = false, strings.TrimSpace(line[index+2:])
+			} else {
+				continue
+			}
+		}
+		if len(line) == 0 {
+			continue
+		} else if strings.HasPrefix(line, "/*") {
+			inBlockComment = !strings.Contains(line[2:], "*/")
+			continue
+		} else if !strings.HasPrefix(line, "//") {
+			break // the header ends at the package clause
+		}
+		if constraint.IsGoBuild(line) {
+			if goBuild != nil {
+				return nil, fmt.Errorf("multiple //go:build lines")
+			}
+			expr, err := constraint.Parse(line)
+			if err != nil {
+				return nil, err
+			}
+			goBuild = expr
+		} else if constraint.IsPlusBuild(line) {
+			expr, err := constraint.Parse(line)
+			if err != nil {
+				return nil, err
+			}
+			if plusBuild == nil {
+				plusBuild = expr
+			} else {
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	if goBuild != nil {
+		return goBuild, nil
+	}
+	return plusBuild, nil
+}
+
+// matchGoFile checks whether the source file of code (which has been read from disk or overlay) is
+// included by the build context as build.Context.MatchFile does, i.e. its name and build constraints
+// are satisfied, where the code is not read again. Path of the file is appended to the ignored slice
+// if it doesn't match.
+func matchGoFile(buildContext *build.Context, srcPath string, src []byte, ignored *[]string) bool {
+	var match = matchFileName(buildContext, srcPath)
+	if match {
+		expr, err := headerConstraintOf(src)
+		if err != nil {
+			return true // let the parser report the file it cannot parse
+		}
+		match = expr == nil || expr.Eval(func(tag string) bool { return matchTag(buildContext, tag) })
+	}
+	if !match && ignored != nil {
+		*ignored = append(*ignored, srcPath)
+	}
+	return match
+}
+
+// recordIgnoredFiles records the files ignored in parsing the directory which declare the same package
+// name as the package in its LoadInfo.
+func recordIgnoredFiles(pkg *Package, dir *parsedDir) {
+	if pkg == nil || pkg.loadInfo == nil || dir == nil {
+		return
+	}
+	for _, ignoredFile := range dir.ignored {
+		if pkgName, ok := dir.ignPkgs[ignoredFile]; ok && pkgName == pkg.pkgName {
+			pkg.loadInfo.IgnoredFiles = append(pkg.loadInfo.IgnoredFiles, ignoredFile)
+		}
+	}
+}
+
+// optionsOfTags returns the LoadOptions targeting the platform given by the build tags, in which the
+// known operating system and architecture are taken as GOOS and GOARCH, and the others are the tags.
+func optionsOfTags(tags []string) *LoadOptions {
+	var opts = &LoadOptions{}
+	for _, tag := range tags {
+		if contains(knownOS, tag) && len(opts.GOOS) == 0 {
+			opts.GOOS = tag
+		} else if contains(knownArch, tag) && len(opts.GOARCH) == 0 {
+			opts.GOARCH = tag
+		} else if len(tag) > 0 {
+			opts.BuildTags = append(opts.BuildTags, tag)
+		}
+	}
+	return opts
+}
+
+// contains checks whether the string is in the slice.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByBuildTag re-parses the directory of this package and returns a new Package consisting of
+// only the source files whose build constraints are satisfied by the tags, which is type-checked
+// again. The tags naming an operating system or architecture (e.g. "linux" or "arm64") select the
+// target platform, such that the variants of platform-specific code could be analyzed side by side.
+//
+// The new Package shares the program and FileSet of this package, but it isn't registered in the
+// program, and this package isn't changed.
+func (pkg *Package) FilterByBuildTag(tags []string) (*Package, error) {
+	// 1. parse the source files satisfying build tags
+	if pkg == nil {
+		return nil, fmt.Errorf("nil package is used")
+	}
+	if len(pkg.dirPath) == 0 || pkg.fileSet == nil {
+		return nil, fmt.Errorf("package not loaded from directory: %s", pkg.pkgPath)
+	}
+	var opts = optionsOfTags(tags)
+	dir, parseErr := parseGoDirectory(pkg.fileSet, pkg.dirPath, opts)
+	if dir == nil || dir.astPkgs[pkg.pkgName] == nil {
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		return nil, fmt.Errorf("%w matching %v in: %s", ErrNoPackages, tags, pkg.dirPath)
+	}
+
+	// 2. construct and type-check the new package
+	var filtered = newPackage(pkg.program, pkg.pkgName, pkg.pkgPath, pkg.dirPath)
+	filtered.fileSet = pkg.fileSet
+	if loadErr := parseGoPackageByFree(filtered, dir, opts); loadErr != nil {
+		return nil, loadErr
+	}
+	recordIgnoredFiles(filtered, dir)
+	return filtered, nil
+}
+
+// constraintOf returns the build constraint expression of the source file, which is parsed from the
+// `//go:build` line (preferred) or the conjunction of legacy `// +build` lines before the package
+// clause. It returns nil if the file has no constraints.
+func (file *SrcFile) constraintOf() (constraint.Expr, error) {
+	if file == nil || file.syntax == nil {
+		return nil, fmt.Errorf("syntax not loaded: %s", file.Path())
+	}
+	var goBuild, plusBuild constraint.Expr
+	for _, group := range file.syntax.Comments {
+		if group.Pos() >= file.syntax.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if constraint.IsGoBuild(comment.Text) {
+				if goBuild != nil {
+					return nil, fmt.Errorf("multiple //go:build lines: %s", file.path)
+				}
+				expr, err := constraint.Parse(comment.Text)
+				if err != nil {
+					return nil, err
+				}
+				goBuild = expr
+			} else if constraint.IsPlusBuild(comment.Text) {
+				expr, err := constraint.Parse(comment.Text)
+				if err != nil {
+					return nil, err
+				}
+				if plusBuild == nil {
+					plusBuild = expr
+				} else {
+					plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+				}
+			}
+		}
+	}
+	if goBuild != nil {
+		return goBuild, nil
+	}
+	return plusBuild, nil
+}
+
+// BuildTags returns the tags referenced in the build constraints of this source file, in the order
+// they first appear in the `//go:build` line (or legacy `// +build` lines if there is no former).
+// It returns empty if the file has no constraints.
+func (file *SrcFile) BuildTags() ([]string, error) {
+	expr, err := file.constraintOf()
+	if err != nil || expr == nil {
+		return nil, err
+	}
+	var tags []string
+	var visited = make(map[string]bool)
+	var collect func(expr constraint.Expr)
+	collect = func(expr constraint.Expr) {
+		switch e := expr.(type) {
+		case *constraint.TagExpr:
+			if !visited[e.Tag] {
+				visited[e.Tag] = true
+				tags = append(tags, e.Tag)
+			}
+		case *constraint.NotExpr:
+			collect(e.X)
+		case *constraint.AndExpr:
+			collect(e.X)
+			collect(e.Y)
+		case *constraint.OrExpr:
+			collect(e.X)
+			collect(e.Y)
+		}
+	}
+	collect(expr)
+	return tags, nil
+}
+
+// HasBuildTag checks whether the tag appears in the build constraints of this source file, either in
+// `//go:build` (e.g. "linux" in `//go:build linux || darwin`) or legacy `// +build` lines. The tag
+// is matched by value rather than evaluated on the current platform, and a negated tag (`!linux`)
+// appears as well. It returns false if the file has no or invalid constraints.
+func (file *SrcFile) HasBuildTag(tag string) bool {
+	tags, err := file.BuildTags()
+	return err == nil && contains(tags, tag)
+}
+
+// fileNamePlatform returns the GOOS and GOARCH implied by the suffixes of the source file name as
+// go/build does, i.e. `*_GOOS_GOARCH.go`, `*_GOOS.go` or `*_GOARCH.go` (optionally with `_test`).
+func fileNamePlatform(path string) []string {
+	var name = strings.TrimSuffix(filepath.Base(path), GoFileSuffix)
+	name = strings.TrimSuffix(name, "_test")
+	var parts = strings.Split(name, "_")
+	var n = len(parts)
+	if n >= 3 && contains(knownOS, parts[n-2]) && contains(knownArch, parts[n-1]) {
+		return []string{parts[n-2], parts[n-1]}
+	}
+	if n >= 2 && (contains(knownOS, parts[n-1]) || contains(knownArch, parts[n-1])) {
+		return []string{parts[n-1]}
+	}
+	return nil
+}
+
+// PlatformConstraints returns the platform constraints of this source file, which are the GOOS and
+// GOARCH implied by its file name, followed by the platform tags (GOOS, GOARCH or "unix") in its
+// build constraints, or empty if the file is not constrained by platform.
+func (file *SrcFile) PlatformConstraints() []string {
+	if file == nil {
+		return nil
+	}
+	var constraints = fileNamePlatform(file.path)
+	tags, _ := file.BuildTags()
+	for _, tag := range tags {
+		if (contains(knownOS, tag) || contains(knownArch, tag) || tag == "unix") &&
+			!contains(constraints, tag) {
+			constraints = append(constraints, tag)
+		}
+	}
+	return constraints
+}
+
+// Platform maps the paths of source files constrained by platform in this package to their tokens
+// of constraints, as SrcFile.PlatformConstraints does.
+func (pkg *Package) Platform() map[string][]string {
+	var platform = make(map[string][]string)
+	if pkg == nil {
+		return platform
+	}
+	for path, srcFile := range pkg.srcFiles {
+		if constraints := srcFile.PlatformConstraints(); len(constraints) > 0 {
+			platform[path] = constraints
+		}
+	}
+	return platform
+}
@@ -0,0 +1,232 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGoFileAsGoBuild(t *testing.T) {
+	var files = map[string]string{
+		"plain.go":         "package p\n",
+		"linux.go":         "//go:build linux\n\npackage p\n",
+		"not_linux.go":     "//go:build !linux\n\npackage p\n",
+		"unix.go":          "// Copyright\n\n//go:build unix && !wasm\n\npackage p\n",
+		"plus.go":          "// +build linux darwin\n// +build amd64\n\npackage p\n",
+		"tagged.go":        "//go:build integration\n\npackage p\n",
+		"ignore.go":        "//go:build ignore\n\npackage p\n",
+		"cgo.go":           "package p\n\nimport \"C\"\n",
+		"a_windows.go":     "package p\n",
+		"a_linux_arm64.go": "package p\n",
+		"a_js_test.go":     "package p\n",
+		"_hidden.go":       "package p\n",
+		"block.go":         "/* license\n//go:build ignore\n*/\n\npackage p\n",
+		"late.go":          "package p\n\n//go:build ignore\n",
+	}
+	dirPath := writeModule(t, map[string]string{})
+	for name, code := range files {
+		if err := os.WriteFile(filepath.Join(dirPath, name), []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, opts := range []*LoadOptions{
+		{GOOS: "linux", GOARCH: "amd64"},
+		{GOOS: "linux", GOARCH: "arm64", BuildTags: []string{"integration"}},
+		{GOOS: "windows", GOARCH: "amd64"},
+		{GOOS: "darwin", GOARCH: "amd64"},
+		{GOOS: "android", GOARCH: "arm64"},
+		{GOOS: "js", GOARCH: "wasm"},
+	} {
+		var buildContext = newBuildContext(opts)
+		for name, code := range files {
+			srcPath := filepath.Join(dirPath, name)
+			want, err := buildContext.MatchFile(dirPath, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchGoFile(buildContext, srcPath, []byte(code), nil); got != want {
+				t.Errorf("%s/%s %v: matchGoFile(%s) = %v, want %v of go/build", opts.GOOS, opts.GOARCH,
+					opts.BuildTags, name, got, want)
+			}
+		}
+	}
+}
+
+func TestMatchGoFileByOverlay(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/ignored_on_disk.go":    "//go:build ignore\n\npackage p\n\nfunc OnDisk() {}\n",
+		"p/ignored_in_overlay.go": "package p\n\nfunc InOverlay() {}\n",
+	})
+	var overlay = map[string][]byte{
+		filepath.Join(rootDir, "p", "ignored_on_disk.go"):    []byte("package p\n\nfunc OnDisk() {}\n"),
+		filepath.Join(rootDir, "p", "ignored_in_overlay.go"): []byte("//go:build ignore\n\npackage p\n"),
+		filepath.Join(rootDir, "p", "only_in_overlay.go"):    []byte("//go:build ignore\n\npackage p // overlay\n"),
+	}
+	prog, err := Load(rootDir, WithOverlay(overlay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, testModulePath+"/p")
+	if pkg.Lookup("OnDisk") == nil || pkg.Lookup("InOverlay") != nil {
+		t.Errorf("files are not filtered by overlay: GoFiles = %v, IgnoredFiles = %v",
+			baseNamesOf(pkg.GoFiles()), baseNamesOf(pkg.LoadInfo().IgnoredFiles))
+	}
+	if got, want := baseNamesOf(pkg.LoadInfo().IgnoredFiles), []string{"ignored_in_overlay.go", "only_in_overlay.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IgnoredFiles = %v, want %v", got, want)
+	}
+}
+
+func TestLoadOnTargetPlatform(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go":       "package p\n",
+		"p/p_linux.go": "package p\n\nconst Platform = \"linux\"\n",
+		"p/p_js.go":    "package p\n\nconst Platform = \"js\"\n",
+		"p/amd64.go":   "//go:build amd64\n\npackage p\n\nconst Wide = true\n",
+		"p/wasm.go":    "//go:build wasm\n\npackage p\n\nconst Wide = false\n",
+	})
+	var fileSets = make(map[string][]string)
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"js", "wasm"}} {
+		prog, err := NewDefaultProgram(rootDir, &LoadOptions{GOOS: platform[0], GOARCH: platform[1]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := mustPackage(t, prog, testModulePath+"/p")
+		if pkg.LoadInfo().HasErrors() {
+			t.Errorf("%s/%s: %v", platform[0], platform[1], pkg.LoadInfo().AllErrors())
+		}
+		fileSets[platform[0]] = baseNamesOf(pkg.GoFiles())
+	}
+	if want := []string{"amd64.go", "p.go", "p_linux.go"}; !reflect.DeepEqual(fileSets["linux"], want) {
+		t.Errorf("files on linux/amd64 = %v, want %v", fileSets["linux"], want)
+	}
+	if want := []string{"p.go", "p_js.go", "wasm.go"}; !reflect.DeepEqual(fileSets["js"], want) {
+		t.Errorf("files on js/wasm = %v, want %v", fileSets["js"], want)
+	}
+}
+
+func TestPackageFilterByBuildTag(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/common.go": "package p\n\nvar Name = name\n",
+		"p/linux.go":  "//go:build linux\n\npackage p\n\nconst name = \"linux\"\n",
+		"p/darwin.go": "//go:build darwin\n\npackage p\n\nconst name = \"darwin\"\n",
+		"p/debug.go":  "//go:build darwin && debug\n\npackage p\n\nconst Debug = true\n",
+	})
+	prog, err := Load(rootDir, WithGOOS("linux"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, testModulePath+"/p")
+	for _, test := range []struct {
+		tags  []string
+		files []string
+	}{
+		{[]string{"linux"}, []string{"common.go", "linux.go"}},
+		{[]string{"darwin"}, []string{"common.go", "darwin.go"}},
+		{[]string{"darwin", "debug"}, []string{"common.go", "darwin.go", "debug.go"}},
+	} {
+		filtered, err := pkg.FilterByBuildTag(test.tags)
+		if err != nil {
+			t.Fatalf("FilterByBuildTag(%v): %v", test.tags, err)
+		}
+		if filtered == pkg || filtered.LoadInfo().HasErrors() {
+			t.Errorf("FilterByBuildTag(%v) = %v with %v", test.tags, filtered, filtered.LoadInfo().AllErrors())
+		}
+		if got := baseNamesOf(filtered.GoFiles()); !reflect.DeepEqual(got, test.files) {
+			t.Errorf("FilterByBuildTag(%v) files = %v, want %v", test.tags, got, test.files)
+		}
+	}
+
+	// the receiver is not changed by filtering
+	if got := baseNamesOf(pkg.GoFiles()); !reflect.DeepEqual(got, []string{"common.go", "linux.go"}) {
+		t.Errorf("files of package = %v, want [common.go linux.go]", got)
+	}
+	if prog.Package(testModulePath+"/p") != pkg {
+		t.Errorf("filtered package is registered in program")
+	}
+	if _, err := newPackage(nil, "p", "example.com/p", "").FilterByBuildTag(nil); err == nil {
+		t.Errorf("FilterByBuildTag(not loaded from directory) = nil, want error")
+	}
+}
+
+func TestSrcFileBuildTags(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"new.go":     "//go:build linux && amd64\n\npackage p\n",
+		"none.go":    "// Package p has no constraints.\npackage p\n",
+		"both.go":    "//go:build linux || !darwin\n// +build linux !darwin\n\npackage p\n",
+		"legacy.go":  "// +build linux,386 darwin\n// +build cgo\n\npackage p\n",
+		"invalid.go": "//go:build linux &&\n\npackage p\n",
+	})
+	for _, test := range []struct {
+		file string
+		tags []string
+		fail bool
+	}{
+		{"new.go", []string{"linux", "amd64"}, false},
+		{"none.go", nil, false},
+		{"both.go", []string{"linux", "darwin"}, false},
+		{"legacy.go", []string{"linux", "386", "darwin", "cgo"}, false},
+		{"invalid.go", nil, true},
+	} {
+		srcFile := pkg.SrcFile(test.file)
+		tags, err := srcFile.BuildTags()
+		if (err != nil) != test.fail || !reflect.DeepEqual(tags, test.tags) {
+			t.Errorf("%s: BuildTags = %v, %v, want %v", test.file, tags, err, test.tags)
+		}
+	}
+}
+
+func TestSrcFileHasBuildTag(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"either.go":  "//go:build linux || darwin\n\npackage p\n",
+		"legacy.go":  "// +build linux,386 !windows\n\npackage p\n",
+		"none.go":    "package p\n",
+		"invalid.go": "//go:build linux &&\n\npackage p\n",
+	})
+	for _, test := range []struct {
+		file string
+		tag  string
+		want bool
+	}{
+		{"either.go", "linux", true},
+		{"either.go", "darwin", true},
+		{"either.go", "windows", false},
+		{"legacy.go", "linux", true},
+		{"legacy.go", "386", true},
+		{"legacy.go", "windows", true}, // negated tags appear in constraints as well
+		{"legacy.go", "darwin", false},
+		{"none.go", "linux", false},
+		{"invalid.go", "linux", false},
+	} {
+		if got := pkg.SrcFile(test.file).HasBuildTag(test.tag); got != test.want {
+			t.Errorf("%s: HasBuildTag(%q) = %v, want %v", test.file, test.tag, got, test.want)
+		}
+	}
+}
+
+func TestPackagePlatform(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a_linux_amd64.go": "package p\n",
+		"b_windows.go":     "package p\n",
+		"c_arm64_test.go":  "package p\n",
+		"d.go":             "//go:build darwin || unix\n\npackage p\n",
+		"e_linux.go":       "//go:build linux && !cgo\n\npackage p\n",
+		"f.go":             "//go:build integration\n\npackage p\n",
+		"g_unknown.go":     "package p\n",
+	})
+	var want = map[string][]string{
+		"a_linux_amd64.go": {"linux", "amd64"},
+		"b_windows.go":     {"windows"},
+		"c_arm64_test.go":  {"arm64"},
+		"d.go":             {"darwin", "unix"},
+		"e_linux.go":       {"linux"},
+	}
+	if got := pkg.Platform(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Platform = %v, want %v", got, want)
+	}
+	for _, name := range []string{"f.go", "g_unknown.go"} {
+		if got := pkg.SrcFile(name).PlatformConstraints(); len(got) != 0 {
+			t.Errorf("%s: PlatformConstraints = %v, want none", name, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the caching of Program, which serializes the metadata of its
+// packages to bytes on disk, so that large projects are not required to be re-loaded every time.
+package golang
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"time"
+)
+
+// programCache is the serializable form of the metadata in a Program.
+type programCache struct {
+	Module   *Module        // Module is the information in `go.mod` file of the program
+	FileSets [][]byte       // FileSets are the serialized token.FileSet shared by packages
+	Packages []packageCache // Packages are the metadata of the packages in the program
+}
+
+// packageCache is the serializable form of the metadata in a Package.
+type packageCache struct {
+	PkgName  string         // PkgName is the short name to refer this from the code of other packages
+	PkgPath  string         // PkgPath is logical path to import this package in file of other package
+	DirPath  string         // DirPath is the absolute path of directory of this package's source file
+	GoFiles  []string       // GoFiles are the set of absolute paths of source files in this package
+	Imports  []string       // Imports are the set of logical paths of packages imported in this package
+	FileSet  int            // FileSet is the index of file set in programCache, or -1 if it's nil
+	LoadInfo *loadInfoCache // LoadInfo is the serializable form of the latest LoadInfo, or nil
+}
+
+// loadInfoCache is the serializable form of LoadInfo (in gob and JSON), in which the errors are kept
+// as messages. The JSON names of fields are stable as documented in LoadInfo.MarshalJSON.
+type loadInfoCache struct {
+	LoadTime     time.Time `json:"loadTime"`     // LoadTime is the time this loading is executed
+	LoadedFiles  []string  `json:"loadedFiles"`  // LoadedFiles are paths of source files loaded
+	IgnoredFiles []string  `json:"ignoredFiles"` // IgnoredFiles are paths of those not be loaded
+	IllTyped     bool      `json:"illTyped"`     // IllTyped is true if any type error occurs in parsing
+	FileErrors   []string  `json:"fileErrors"`   // FileErrors are messages of errors when parsing the file
+	TypeErrors   []string  `json:"typeErrors"`   // TypeErrors are messages of errors in checking the types
+	DepsErrors   []string  `json:"depsErrors"`   // DepsErrors are messages of errors in dependency imports
+
+	// FileLoadTimes map from files to durations (in nanoseconds) of reading and parsing them
+	FileLoadTimes map[string]time.Duration `json:"fileLoadTimes,omitempty"`
+}
+
+// newLoadInfoCache returns the serializable form of LoadInfo, or nil if it is nil.
+func newLoadInfoCache(info *LoadInfo) *loadInfoCache {
+	if info == nil {
+		return nil
+	}
+	return &loadInfoCache{
+		LoadTime:      info.LoadTime,
+		LoadedFiles:   info.LoadedFiles,
+		IgnoredFiles:  info.IgnoredFiles,
+		IllTyped:      info.IllTyped,
+		FileErrors:    errorMessagesOf(info.FileErrors),
+		TypeErrors:    errorMessagesOf(info.TypeErrors),
+		DepsErrors:    errorMessagesOf(info.DepsErrors),
+		FileLoadTimes: info.FileLoadTimes,
+	}
+}
+
+// loadInfo restores the LoadInfo from its serializable form, of which errors are created from the
+// messages.
+func (cache *loadInfoCache) loadInfo() *LoadInfo {
+	return &LoadInfo{
+		LoadTime:      cache.LoadTime,
+		LoadedFiles:   cache.LoadedFiles,
+		IgnoredFiles:  cache.IgnoredFiles,
+		IllTyped:      cache.IllTyped,
+		FileErrors:    errorsOfMessages(cache.FileErrors),
+		TypeErrors:    errorsOfMessages(cache.TypeErrors),
+		DepsErrors:    errorsOfMessages(cache.DepsErrors),
+		FileLoadTimes: cache.FileLoadTimes,
+	}
+}
+
+// MarshalJSON encodes the LoadInfo as a JSON object with the stable fields below, in which errors
+// are encoded as their messages since error isn't natively JSON-able:
+//
+//	loadTime      (string) LoadTime in RFC 3339 format
+//	loadedFiles   ([]string) LoadedFiles
+//	ignoredFiles  ([]string) IgnoredFiles
+//	illTyped      (bool) IllTyped
+//	fileErrors    ([]string) messages of FileErrors
+//	typeErrors    ([]string) messages of TypeErrors
+//	depsErrors    ([]string) messages of DepsErrors
+//	fileLoadTimes (map[string]int) FileLoadTimes in nanoseconds, omitted if empty
+//
+// IsCached is not encoded since it describes how the LoadInfo is restored rather than the loading.
+// The receiver is a value, such that both LoadInfo and *LoadInfo (nil as null) use the encoding.
+func (info LoadInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newLoadInfoCache(&info))
+}
+
+// UnmarshalJSON decodes the LoadInfo from JSON produced by MarshalJSON, of which errors are created
+// from their messages.
+func (info *LoadInfo) UnmarshalJSON(data []byte) error {
+	var cache loadInfoCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return err
+	}
+	*info = *cache.loadInfo()
+	return nil
+}
+
+// errorMessagesOf returns the messages of non-nil errors in the slice.
+func errorMessagesOf(errs []error) []string {
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	return messages
+}
+
+// errorsOfMessages returns the errors created from the messages in the slice.
+func errorsOfMessages(messages []string) []error {
+	var errs []error
+	for _, message := range messages {
+		errs = append(errs, errors.New(message))
+	}
+	return errs
+}
+
+// Serialize encodes the metadata of program's packages (including their paths, names, imports and
+// LoadInfo) along with their token.FileSet into bytes using encoding/gob.
+//
+// The syntax trees and types.Info of packages cannot be serialized, so they are not included.
+func (prog *Program) Serialize() ([]byte, error) {
+	if prog == nil {
+		return nil, fmt.Errorf("nil program is used")
+	}
+
+	// 1. serialize the file sets shared by the packages
+	cache := &programCache{Module: prog.module}
+	fileSetIndex := make(map[*token.FileSet]int)
+	for _, pkg := range prog.AllPackages() {
+		if pkg.fileSet == nil {
+			continue
+		}
+		if _, ok := fileSetIndex[pkg.fileSet]; ok {
+			continue
+		}
+		var buffer bytes.Buffer
+		if err := pkg.fileSet.Write(gob.NewEncoder(&buffer).Encode); err != nil {
+			return nil, err
+		}
+		fileSetIndex[pkg.fileSet] = len(cache.FileSets)
+		cache.FileSets = append(cache.FileSets, buffer.Bytes())
+	}
+
+	// 2. construct the metadata of each package
+	for _, pkg := range prog.AllPackages() {
+		pkgCache := packageCache{
+			PkgName: pkg.pkgName,
+			PkgPath: pkg.pkgPath,
+			DirPath: pkg.dirPath,
+			GoFiles: pkg.GoFiles(),
+			Imports: pkg.imports,
+			FileSet: -1,
+		}
+		if index, ok := fileSetIndex[pkg.fileSet]; ok && pkg.fileSet != nil {
+			pkgCache.FileSet = index
+		}
+		pkgCache.LoadInfo = newLoadInfoCache(pkg.loadInfo)
+		cache.Packages = append(cache.Packages, pkgCache)
+	}
+
+	// 3. encode the program's metadata into bytes
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(cache); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Deserialize resets the program with the metadata decoded from the bytes produced by Serialize.
+//
+// The packages are metadata-only: their syntax trees and type information are nil, and the flag
+// LoadInfo.IsCached is set, so callers could selectively re-parse the packages as required, e.g.
+// by Program.LoadByPath which doesn't take the cached packages as loaded.
+func (prog *Program) Deserialize(data []byte) error {
+	if prog == nil {
+		return fmt.Errorf("nil program is used")
+	}
+
+	// 1. decode the program's metadata from bytes
+	var cache programCache
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cache); err != nil {
+		return err
+	}
+	var fileSets []*token.FileSet
+	for _, fileSetBytes := range cache.FileSets {
+		fileSet := token.NewFileSet()
+		if err := fileSet.Read(gob.NewDecoder(bytes.NewReader(fileSetBytes)).Decode); err != nil {
+			return err
+		}
+		fileSets = append(fileSets, fileSet)
+	}
+
+	// 2. reconstruct the packages in the program
+	prog.module = cache.Module
+	prog.pkgSet = make(map[string]*Package)
+	prog.fileSet = token.NewFileSet()
+	if len(fileSets) == 1 {
+		prog.fileSet = fileSets[0] // the re-parsed files are positioned after the cached ones
+	}
+	prog.importer, prog.ssaProg, prog.options, prog.parsedDirs = nil, nil, nil, nil
+	for _, pkgCache := range cache.Packages {
+		pkg := prog.newPackage(pkgCache.PkgName, pkgCache.PkgPath, pkgCache.DirPath)
+		if pkg == nil {
+			return fmt.Errorf("can't new package: %s", pkgCache.PkgPath)
+		}
+		for _, goFile := range pkgCache.GoFiles {
+			pkg.newSrcFile(goFile)
+		}
+		pkg.imports = pkgCache.Imports
+		if pkgCache.FileSet >= 0 && pkgCache.FileSet < len(fileSets) {
+			pkg.fileSet = fileSets[pkgCache.FileSet]
+		}
+		if loadInfo := pkgCache.LoadInfo; loadInfo != nil {
+			pkg.loadInfo = loadInfo.loadInfo()
+			pkg.loadInfo.IsCached = true
+		}
+	}
+
+	// 3. link the packages to their external tests
+	for _, pkg := range prog.pkgSet {
+		testPkg := prog.Package(pkg.pkgPath + "_test")
+		if testPkg != nil && testPkg.dirPath == pkg.dirPath && testPkg.pkgName == pkg.pkgName+"_test" {
+			pkg.testPkg = testPkg
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package golang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestProgramSerializeRoundTrip(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\nimport \"example.com/m/b\"\n\nfunc A() int { return b.B() }\n",
+		"a/a_test.go": "package a_test\n\nimport \"example.com/m/a\"\n\nvar _ = a.A\n",
+		"b/b.go":      "package b\n\nfunc B() int { return 1 }\n",
+		"c/c.go":      "package c\n\nfunc C() { undefined() }\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := prog.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cached = &Program{}
+	if err := cached.Deserialize(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var pkgPathsOf = func(prog *Program) []string {
+		var pkgPaths []string
+		for _, pkg := range prog.AllPackages() {
+			pkgPaths = append(pkgPaths, pkg.PkgPath())
+		}
+		sort.Strings(pkgPaths)
+		return pkgPaths
+	}
+	if got, want := pkgPathsOf(cached), pkgPathsOf(prog); !reflect.DeepEqual(got, want) {
+		t.Fatalf("packages = %v, want %v", got, want)
+	}
+	if cached.Module().ModuleName != testModulePath {
+		t.Errorf("module = %q, want %q", cached.Module().ModuleName, testModulePath)
+	}
+	for _, pkg := range prog.AllPackages() {
+		var restored = cached.Package(pkg.PkgPath())
+		if restored.PkgName() != pkg.PkgName() || restored.DirPath() != pkg.DirPath() {
+			t.Errorf("%s: name %q in %s, want %q in %s", pkg.PkgPath(), restored.PkgName(),
+				restored.DirPath(), pkg.PkgName(), pkg.DirPath())
+		}
+		var gotFiles, wantFiles = restored.GoFiles(), pkg.GoFiles()
+		sort.Strings(gotFiles)
+		sort.Strings(wantFiles)
+		if !reflect.DeepEqual(gotFiles, wantFiles) || !reflect.DeepEqual(restored.Imports(), pkg.Imports()) {
+			t.Errorf("%s: files %v importing %v, want %v importing %v", pkg.PkgPath(), gotFiles,
+				restored.Imports(), wantFiles, pkg.Imports())
+		}
+		var info, restoredInfo = pkg.LoadInfo(), restored.LoadInfo()
+		if !restoredInfo.IsCached || info.IsCached {
+			t.Errorf("%s: IsCached = %v, want true", pkg.PkgPath(), restoredInfo.IsCached)
+		}
+		if !restoredInfo.LoadTime.Equal(info.LoadTime) || restoredInfo.IllTyped != info.IllTyped ||
+			len(restoredInfo.TypeErrors) != len(info.TypeErrors) {
+			t.Errorf("%s: LoadInfo = %+v, want %+v", pkg.PkgPath(), restoredInfo, info)
+		}
+		if restored.TypePkg() != nil || restored.TypeInfo() != nil {
+			t.Errorf("%s: type information is restored", pkg.PkgPath())
+		}
+
+		// the positions recorded before are resolved by the restored FileSet
+		for _, srcFile := range pkg.syntaxFiles() {
+			for _, decl := range srcFile.Syntax().Decls {
+				got, want := restored.FileSet().Position(decl.Pos()), pkg.FileSet().Position(decl.Pos())
+				if got != want {
+					t.Errorf("%s: position = %v, want %v", pkg.PkgPath(), got, want)
+				}
+			}
+		}
+	}
+
+	var a, aTest = cached.Package(testModulePath + "/a"), cached.Package(testModulePath + "/a_test")
+	if a.TestPackage() == nil || a.TestPackage() != aTest {
+		t.Errorf("TestPackage of a = %v, want the restored %v", a.TestPackage(), aTest)
+	}
+	if b := cached.Package(testModulePath + "/b"); b.TestPackage() != nil {
+		t.Errorf("TestPackage of b = %v, want nil", b.TestPackage())
+	}
+	if err := cached.Deserialize([]byte("not gob")); err == nil {
+		t.Errorf("Deserialize(invalid) = nil, want error")
+	}
+}
+
+func TestProgramDeserializeReload(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\nfunc A() int { return b.B() }\n",
+		"b/b.go": "package b\n\nfunc B() int { return 1 }\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := prog.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cached = &Program{}
+	if err := cached.Deserialize(data); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := cached.LoadByPath(testModulePath + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.LoadInfo().IsCached || a.LoadInfo().IllTyped || a.TypePkg() == nil || a.TypeInfo() == nil {
+		t.Fatalf("LoadByPath(a) = %+v, want the re-parsed package", a.LoadInfo())
+	}
+	if !reflect.DeepEqual(a.Imports(), []string{testModulePath + "/b"}) || len(a.GoFiles()) != 1 {
+		t.Errorf("a: files %v importing %v, want a.go importing b", a.GoFiles(), a.Imports())
+	}
+	var b = cached.Package(testModulePath + "/b")
+	if b.LoadInfo().IsCached || b.TypePkg() == nil || a.TypePkg().Imports()[0] != b.TypePkg() {
+		t.Errorf("b is not re-parsed as the import of a: %+v", b.LoadInfo())
+	}
+	if err := a.Reload(); err != nil {
+		t.Errorf("Reload = %v, want nil", err)
+	}
+}
+
+func TestLoadInfoJSON(t *testing.T) {
+	var info = &LoadInfo{
+		LoadTime:      time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		LoadedFiles:   []string{"/m/p/a.go"},
+		IgnoredFiles:  []string{"/m/p/a_windows.go"},
+		IllTyped:      true,
+		FileErrors:    []error{errors.New("a.go:1:1: expected 'package'")},
+		TypeErrors:    []error{fmt.Errorf("a.go:3:9: undefined: %s", "x")},
+		DepsErrors:    []error{errors.New("can't import: example.com/dep")},
+		FileLoadTimes: map[string]time.Duration{"/m/p/a.go": 1500},
+		IsCached:      true,
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"loadTime", "loadedFiles", "ignoredFiles", "illTyped", "fileErrors",
+		"typeErrors", "depsErrors", "fileLoadTimes"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("field %q is not encoded in %s", name, data)
+		}
+	}
+	if fields["loadTime"] != "2024-05-01T12:30:00Z" {
+		t.Errorf("loadTime = %v, want RFC 3339", fields["loadTime"])
+	}
+
+	var decoded LoadInfo
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.LoadTime.Equal(info.LoadTime) || decoded.IllTyped != info.IllTyped || decoded.IsCached ||
+		!reflect.DeepEqual(decoded.LoadedFiles, info.LoadedFiles) ||
+		!reflect.DeepEqual(decoded.IgnoredFiles, info.IgnoredFiles) ||
+		!reflect.DeepEqual(decoded.FileLoadTimes, info.FileLoadTimes) {
+		t.Errorf("decoded = %+v, want %+v", decoded, info)
+	}
+	for _, test := range []struct{ got, want []error }{
+		{decoded.FileErrors, info.FileErrors},
+		{decoded.TypeErrors, info.TypeErrors},
+		{decoded.DepsErrors, info.DepsErrors},
+	} {
+		if !reflect.DeepEqual(errorMessagesOf(test.got), errorMessagesOf(test.want)) {
+			t.Errorf("errors = %v, want %v", test.got, test.want)
+		}
+	}
+	if data, err := json.Marshal((*LoadInfo)(nil)); err != nil || string(data) != "null" {
+		t.Errorf("Marshal(nil) = %s, %v, want null", data, err)
+	}
+
+	// the values (e.g. in maps, which aren't addressable) are encoded in the same way
+	valueData, err := json.Marshal(map[string]LoadInfo{"p": *info})
+	if err != nil || string(valueData) != `{"p":`+string(data)+`}` {
+		t.Errorf("Marshal(map[string]LoadInfo) = %s, %v, want {\"p\":%s}", valueData, err, data)
+	}
+}
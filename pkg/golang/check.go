@@ -0,0 +1,919 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the heuristic checks on the syntax and types of SrcFile and
+// Package, which report the suspicious code patterns commonly detected by linters.
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// isNamedTypeOf checks whether the type is the named type declared in package path with the name.
+func isNamedTypeOf(typ types.Type, pkgPath, name string) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj() == nil || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == pkgPath && named.Obj().Name() == name
+}
+
+// isContextType checks whether the type is the context.Context
+func isContextType(typ types.Type) bool {
+	return isNamedTypeOf(typ, "context", "Context")
+}
+
+// referencesAnyOf checks whether the expression uses any of the objects in the set.
+func referencesAnyOf(expr ast.Expr, info *types.Info, objects map[types.Object]bool) bool {
+	var found bool
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && objects[info.Uses[ident]] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// ContextMissingDiagnostic reports a call in the function receiving context.Context, which calls a
+// function accepting context.Context as first parameter without passing the received context.
+type ContextMissingDiagnostic struct {
+	Func   *ast.FuncDecl  // Func is the declaration of function that receives the context
+	Callee string         // Callee is the expression of the function being called
+	Pos    token.Position // Pos is the position of the call in the source file
+}
+
+// ContextPropagation finds the functions with a context.Context parameter, which call the functions
+// accepting context.Context as first parameter, but don't pass the received context (or the one
+// derived from it in the function body) as argument. It returns nil if type info isn't loaded.
+func (file *SrcFile) ContextPropagation() []ContextMissingDiagnostic {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var info = file.pkg.typInfo
+	var diagnostics []ContextMissingDiagnostic
+	for _, decl := range file.syntax.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil || funcDecl.Type.Params == nil {
+			continue
+		}
+
+		// 1. collect the context parameters received by function
+		var contexts = make(map[types.Object]bool)
+		for _, field := range funcDecl.Type.Params.List {
+			for _, name := range field.Names {
+				if obj := info.Defs[name]; obj != nil && isContextType(obj.Type()) {
+					contexts[obj] = true
+				}
+			}
+		}
+		if len(contexts) == 0 {
+			continue
+		}
+
+		// 2. collect the contexts derived in the function body
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok {
+				if obj, ok := info.Defs[ident].(*types.Var); ok && isContextType(obj.Type()) {
+					contexts[obj] = true
+				}
+			}
+			return true
+		})
+
+		// 3. find the calls without passing the contexts
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			signature, ok := info.TypeOf(call.Fun).(*types.Signature)
+			if !ok || signature.Params().Len() == 0 || !isContextType(signature.Params().At(0).Type()) {
+				return true
+			}
+			if !referencesAnyOf(call.Args[0], info, contexts) {
+				diagnostics = append(diagnostics, ContextMissingDiagnostic{
+					Func:   funcDecl,
+					Callee: types.ExprString(call.Fun),
+					Pos:    file.pkg.fileSet.Position(call.Pos()),
+				})
+			}
+			return true
+		})
+	}
+	return diagnostics
+}
+
+// funcNameOf returns the name of the declared function as "Foo", or "T.Foo" and "(*T).Foo" for the
+// methods, which is the form accepted by Package.SSAFunc.
+func funcNameOf(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return funcDecl.Name.Name
+	}
+	var recvType = funcDecl.Recv.List[0].Type
+	var isPointer bool
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		recvType, isPointer = star.X, true
+	}
+	switch typ := recvType.(type) {
+	case *ast.IndexExpr:
+		recvType = typ.X
+	case *ast.IndexListExpr:
+		recvType = typ.X
+	}
+	if isPointer {
+		return "(*" + types.ExprString(recvType) + ")." + funcDecl.Name.Name
+	}
+	return types.ExprString(recvType) + "." + funcDecl.Name.Name
+}
+
+// isStringType checks whether the underlying type is the string.
+func isStringType(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+// ConcatDiagnostic reports a string concatenation by `+` or `+=` in a loop, which allocates a new
+// string in each iteration and takes the quadratic time to build the result.
+type ConcatDiagnostic struct {
+	Func string         // Func is the name of function where the concatenation occurs
+	Pos  token.Position // Pos is the position of the concatenation in the source file
+}
+
+// StringConcatPatterns finds the assignments in loops of each function, that concatenate strings
+// as `s += x` or `s = s + x`, which are better built by strings.Builder. It returns nil if type
+// info isn't loaded.
+func (pkg *Package) StringConcatPatterns() []ConcatDiagnostic {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var info = pkg.typInfo
+	var diagnostics []ConcatDiagnostic
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, decl := range srcFile.syntax.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+			inspectWithStack(funcDecl.Body, func(node ast.Node, stack []ast.Node) bool {
+				if stmt, ok := node.(*ast.AssignStmt); ok && inLoop(stack) && isStringConcat(stmt, info) {
+					diagnostics = append(diagnostics, ConcatDiagnostic{
+						Func: funcNameOf(funcDecl),
+						Pos:  pkg.fileSet.Position(stmt.Pos()),
+					})
+				}
+				return true
+			})
+		}
+	}
+	return diagnostics
+}
+
+// isStringConcat checks whether the assignment concatenates strings as `s += x` or `s = s + x`.
+func isStringConcat(stmt *ast.AssignStmt, info *types.Info) bool {
+	if len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 || !isStringType(info.TypeOf(stmt.Lhs[0])) {
+		return false
+	}
+	switch stmt.Tok {
+	case token.ADD_ASSIGN:
+		return true
+	case token.ASSIGN:
+		var operand = stmt.Rhs[0]
+		for { // find the leftmost operand of concatenation
+			if paren, ok := operand.(*ast.ParenExpr); ok {
+				operand = paren.X
+			} else if binary, ok := operand.(*ast.BinaryExpr); ok && binary.Op == token.ADD {
+				operand = binary.X
+			} else {
+				break
+			}
+		}
+		return operand != stmt.Rhs[0] && types.ExprString(operand) == types.ExprString(stmt.Lhs[0])
+	}
+	return false
+}
+
+// isErrorType checks whether the type implements the error interface.
+func isErrorType(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	errorType, _ := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	return errorType != nil && types.Implements(typ, errorType)
+}
+
+// ErrorReturns returns the top-level functions and methods in the source file, of which any result
+// type implements the error interface. It returns nil if type info isn't loaded.
+func (file *SrcFile) ErrorReturns() []*ast.FuncDecl {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var functions []*ast.FuncDecl
+	for _, funcDecl := range file.Functions() {
+		if funcDecl.Type.Results == nil {
+			continue
+		}
+		for _, field := range funcDecl.Type.Results.List {
+			if isErrorType(file.pkg.typInfo.TypeOf(field.Type)) {
+				functions = append(functions, funcDecl)
+				break
+			}
+		}
+	}
+	return functions
+}
+
+// IgnoredErrors finds the calls of multi-value functions in the source file, of which error result
+// is assigned to the blank identifier, e.g. `x, _ := f()`. It returns nil if type info isn't loaded.
+func (file *SrcFile) IgnoredErrors() []token.Position {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var info = file.pkg.typInfo
+	var positions []token.Position
+	var checkCall = func(lhs []ast.Expr, rhs []ast.Expr) {
+		if len(rhs) != 1 || len(lhs) < 2 {
+			return
+		}
+		call, ok := rhs[0].(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		results, ok := info.TypeOf(call).(*types.Tuple)
+		if !ok || results.Len() != len(lhs) {
+			return
+		}
+		for i, expr := range lhs {
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name == "_" && isErrorType(results.At(i).Type()) {
+				positions = append(positions, file.pkg.fileSet.Position(call.Pos()))
+				return
+			}
+		}
+	}
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		switch stmt := node.(type) {
+		case *ast.AssignStmt:
+			checkCall(stmt.Lhs, stmt.Rhs)
+		case *ast.ValueSpec:
+			var lhs = make([]ast.Expr, 0, len(stmt.Names))
+			for _, name := range stmt.Names {
+				lhs = append(lhs, name)
+			}
+			checkCall(lhs, stmt.Values)
+		}
+		return true
+	})
+	return positions
+}
+
+// isFuncOf checks whether the called function is the one declared in package path with the name.
+func isFuncOf(call *ast.CallExpr, info *types.Info, pkgPath, name string) bool {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return false
+	}
+	function, ok := info.Uses[ident].(*types.Func)
+	return ok && function.Pkg() != nil && function.Pkg().Path() == pkgPath && function.Name() == name
+}
+
+// WrapDiagnostic reports a call of fmt.Errorf formatting an error without the `%w` verb, of which
+// the result can't be unwrapped to the error by errors.Is and errors.As.
+type WrapDiagnostic struct {
+	Func   string         // Func is the name of function where fmt.Errorf is called
+	Format string         // Format is the format string passed to fmt.Errorf
+	Pos    token.Position // Pos is the position of the call in the source file
+}
+
+// ErrorWrapping finds the calls of fmt.Errorf in functions of the package, which take an error in
+// arguments but the literal format string doesn't contain `%w`. The calls formatting no errors are
+// not reported since there is nothing to wrap. It returns nil if type info isn't loaded.
+func (pkg *Package) ErrorWrapping() []WrapDiagnostic {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var info = pkg.typInfo
+	var diagnostics []WrapDiagnostic
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, funcDecl := range srcFile.Functions() {
+			if funcDecl.Body == nil {
+				continue
+			}
+			ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok || len(call.Args) < 2 || !isFuncOf(call, info, "fmt", "Errorf") {
+					return true
+				}
+				literal, ok := call.Args[0].(*ast.BasicLit)
+				if !ok || literal.Kind != token.STRING {
+					return true
+				}
+				format, err := strconv.Unquote(literal.Value)
+				if err != nil || strings.Contains(format, "%w") {
+					return true
+				}
+				for _, arg := range call.Args[1:] {
+					if isErrorType(info.TypeOf(arg)) {
+						diagnostics = append(diagnostics, WrapDiagnostic{
+							Func:   funcNameOf(funcDecl),
+							Format: format,
+							Pos:    pkg.fileSet.Position(call.Pos()),
+						})
+						break
+					}
+				}
+				return true
+			})
+		}
+	}
+	return diagnostics
+}
+
+// TypeAssertInfo is a type assertion in the source file, which panics on failure if it is unsafe.
+type TypeAssertInfo struct {
+	Expr *ast.TypeAssertExpr // Expr is the type assertion expression as `x.(T)`
+	Pos  token.Position      // Pos is the position of the type assertion in source file
+	Type string              // Type is the source of the asserted type T
+	Safe bool                // Safe is true if the assertion is in the comma-ok form `v, ok := x.(T)`
+}
+
+// TypeAssertions returns the type assertions in the source file sorted by their positions, except
+// the guards of type switches `x.(type)`, which never panic. It returns nil if syntax isn't loaded.
+func (file *SrcFile) TypeAssertions() []TypeAssertInfo {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return nil
+	}
+	var assertions []TypeAssertInfo
+	inspectWithStack(file.syntax, func(node ast.Node, stack []ast.Node) bool {
+		expr, ok := node.(*ast.TypeAssertExpr)
+		if !ok || expr.Type == nil {
+			return true
+		}
+		var safe bool
+		if len(stack) > 1 {
+			switch parent := stack[len(stack)-2].(type) {
+			case *ast.AssignStmt:
+				safe = len(parent.Lhs) == 2 && len(parent.Rhs) == 1 && parent.Rhs[0] == expr
+			case *ast.ValueSpec:
+				safe = len(parent.Names) == 2 && len(parent.Values) == 1 && parent.Values[0] == expr
+			}
+		}
+		assertions = append(assertions, TypeAssertInfo{
+			Expr: expr,
+			Pos:  file.pkg.fileSet.Position(expr.Pos()),
+			Type: types.ExprString(expr.Type),
+			Safe: safe,
+		})
+		return true
+	})
+	return assertions // the nodes are visited in the order of positions
+}
+
+// RegexpDiagnostic reports a regular expression compiled in the body of function, which is compiled
+// again in each call and better be compiled once in a package-level variable.
+type RegexpDiagnostic struct {
+	Func string         // Func is the name of function where the regular expression is compiled
+	Pos  token.Position // Pos is the position of the call compiling the regular expression
+}
+
+// RegexpCompileOutsideFunc finds the calls of regexp.Compile and regexp.MustCompile (including the
+// POSIX ones) with constant patterns in function bodies, which should be moved outside the function
+// to the package-level variables. Those in init functions are not reported since they run once. It
+// returns nil if type info isn't loaded.
+func (file *SrcFile) RegexpCompileOutsideFunc() []RegexpDiagnostic {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var info = file.pkg.typInfo
+	var diagnostics []RegexpDiagnostic
+	for _, funcDecl := range file.Functions() {
+		if funcDecl.Body == nil || (funcDecl.Recv == nil && funcDecl.Name.Name == "init") {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			for _, name := range []string{"Compile", "MustCompile", "CompilePOSIX", "MustCompilePOSIX"} {
+				if isFuncOf(call, info, "regexp", name) && info.Types[call.Args[0]].Value != nil {
+					diagnostics = append(diagnostics, RegexpDiagnostic{
+						Func: funcNameOf(funcDecl),
+						Pos:  file.pkg.fileSet.Position(call.Pos()),
+					})
+					break
+				}
+			}
+			return true
+		})
+	}
+	return diagnostics
+}
+
+// NilDiagnostic reports the first dereference of a pointer or interface parameter in the function,
+// which is not guarded by a nil check.
+type NilDiagnostic struct {
+	Func string         // Func is the name of function receiving the parameter
+	Var  string         // Var is the name of parameter being dereferenced
+	Pos  token.Position // Pos is the position of the selector dereferencing the parameter
+}
+
+// isNilComparison checks whether the expression compares the object with nil by the operator, e.g.
+// `x == nil`, `nil != x`, or any operand of the chained conditions by chainOp (&& or ||).
+func isNilComparison(expr ast.Expr, info *types.Info, obj types.Object, op, chainOp token.Token) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return isNilComparison(e.X, info, obj, op, chainOp)
+	case *ast.BinaryExpr:
+		if e.Op == chainOp {
+			return isNilComparison(e.X, info, obj, op, chainOp) ||
+				isNilComparison(e.Y, info, obj, op, chainOp)
+		}
+		if e.Op != op {
+			return false
+		}
+		var isObj = func(x ast.Expr) bool {
+			ident, ok := x.(*ast.Ident)
+			return ok && info.Uses[ident] == obj
+		}
+		var isNil = func(x ast.Expr) bool {
+			ident, ok := x.(*ast.Ident)
+			_, isNil := info.Uses[ident].(*types.Nil)
+			return ok && isNil
+		}
+		return (isObj(e.X) && isNil(e.Y)) || (isNil(e.X) && isObj(e.Y))
+	}
+	return false
+}
+
+// isNilGuarded checks whether the innermost node in the stack is guarded from the object being nil:
+//  1. it is in the body of `if x != nil {...}` or the else branch of `if x == nil {...}`;
+//  2. it is in the right operand of `x != nil && ...` or `x == nil || ...`;
+//  3. it follows `if x == nil {...}` in the same block, which is expected to return.
+func isNilGuarded(stack []ast.Node, info *types.Info, obj types.Object) bool {
+	for i := len(stack) - 2; i >= 0; i-- {
+		var child = stack[i+1]
+		switch parent := stack[i].(type) {
+		case *ast.IfStmt:
+			if child == parent.Body && isNilComparison(parent.Cond, info, obj, token.NEQ, token.LAND) {
+				return true
+			}
+			if child == parent.Else && isNilComparison(parent.Cond, info, obj, token.EQL, token.LOR) {
+				return true
+			}
+		case *ast.BinaryExpr:
+			if child == parent.Y && parent.Op == token.LAND &&
+				isNilComparison(parent.X, info, obj, token.NEQ, token.LAND) {
+				return true
+			}
+			if child == parent.Y && parent.Op == token.LOR &&
+				isNilComparison(parent.X, info, obj, token.EQL, token.LOR) {
+				return true
+			}
+		case *ast.BlockStmt:
+			for _, stmt := range parent.List {
+				if stmt == child {
+					break
+				}
+				if ifStmt, ok := stmt.(*ast.IfStmt); ok &&
+					isNilComparison(ifStmt.Cond, info, obj, token.EQL, token.LOR) {
+					return true
+				}
+			}
+		case *ast.FuncLit:
+			return false
+		}
+	}
+	return false
+}
+
+// NilChecks finds the parameters of pointer or interface types in functions of package, of which a
+// field or method is selected without a preceding nil check (see isNilGuarded), and reports their
+// first unguarded selections.
+//
+// It is a syntactic heuristic rather than a pointer analysis: the receivers are excluded (as they're
+// commonly non-nil), and so are the local variables (which are mostly initialized by constructors).
+// It returns nil if type info isn't loaded.
+func (pkg *Package) NilChecks() []NilDiagnostic {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var info = pkg.typInfo
+	var diagnostics []NilDiagnostic
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, funcDecl := range srcFile.Functions() {
+			if funcDecl.Body == nil || funcDecl.Type.Params == nil {
+				continue
+			}
+			// 1. collect the parameters of pointer or interface types
+			var params = make(map[types.Object]bool)
+			for _, field := range funcDecl.Type.Params.List {
+				for _, name := range field.Names {
+					if obj := info.Defs[name]; obj != nil && isNillableRef(obj.Type()) {
+						params[obj] = true
+					}
+				}
+			}
+			if len(params) == 0 {
+				continue
+			}
+
+			// 2. report the first unguarded selection of each parameter
+			var reported = make(map[types.Object]bool)
+			inspectWithStack(funcDecl.Body, func(node ast.Node, stack []ast.Node) bool {
+				selector, ok := node.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				ident, ok := selector.X.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj := info.Uses[ident]
+				if !params[obj] || reported[obj] || isNilGuarded(stack, info, obj) {
+					return true
+				}
+				reported[obj] = true
+				diagnostics = append(diagnostics, NilDiagnostic{
+					Func: funcNameOf(funcDecl),
+					Var:  ident.Name,
+					Pos:  pkg.fileSet.Position(selector.Pos()),
+				})
+				return true
+			})
+		}
+	}
+	return diagnostics
+}
+
+// isNillableRef checks whether the type is a pointer or an interface, of which a selection panics
+// (or calls a method on nil) if it is nil.
+func isNillableRef(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	switch typ.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// ReceiverMethod is a method declared with either a pointer or value receiver.
+type ReceiverMethod struct {
+	Name string         // Name is the name of method
+	Pos  token.Position // Pos is the position of method's declaration in source file
+}
+
+// ReceiverDiagnostic reports a named type in the package of which methods are declared with both
+// the pointer and value receivers.
+type ReceiverDiagnostic struct {
+	Type           string           // Type is the name of receiver type
+	PointerMethods []ReceiverMethod // PointerMethods are the methods with pointer receivers
+	ValueMethods   []ReceiverMethod // ValueMethods are the methods with value receivers
+}
+
+// isStringerMethod checks whether the method is `String() string`, which is commonly declared with
+// value receiver to print the values of the type using pointer receivers otherwise.
+func isStringerMethod(method *types.Func) bool {
+	signature, ok := method.Type().(*types.Signature)
+	if !ok || method.Name() != "String" || signature.Params().Len() != 0 || signature.Results().Len() != 1 {
+		return false
+	}
+	basic, ok := signature.Results().At(0).Type().(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
+
+// ConsistentReceivers finds the named types declared in the package, of which methods are mixed of
+// pointer and value receivers, in the order of type names. The types with basic underlying types
+// are excluded, and the value method `String() string` doesn't count as it implements fmt.Stringer
+// for both values and pointers. It returns nil if the package is not type-checked.
+func (pkg *Package) ConsistentReceivers() []ReceiverDiagnostic {
+	var diagnostics []ReceiverDiagnostic
+	for _, named := range pkg.NamedTypes() {
+		if _, ok := named.Underlying().(*types.Basic); ok || named.NumMethods() < 2 {
+			continue
+		}
+		var diagnostic = ReceiverDiagnostic{Type: named.Obj().Name()}
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+			signature, ok := method.Type().(*types.Signature)
+			if !ok || signature.Recv() == nil {
+				continue
+			}
+			var receiverMethod = ReceiverMethod{Name: method.Name()}
+			if pkg.fileSet != nil {
+				receiverMethod.Pos = pkg.fileSet.Position(method.Pos())
+			}
+			if _, isPointer := signature.Recv().Type().(*types.Pointer); isPointer {
+				diagnostic.PointerMethods = append(diagnostic.PointerMethods, receiverMethod)
+			} else if !isStringerMethod(method) {
+				diagnostic.ValueMethods = append(diagnostic.ValueMethods, receiverMethod)
+			}
+		}
+		if len(diagnostic.PointerMethods) > 0 && len(diagnostic.ValueMethods) > 0 {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+// CaptureDiagnostic is a variable of the enclosing function captured by a closure (function literal).
+type CaptureDiagnostic struct {
+	Closure     *ast.FuncLit // Closure is the function literal capturing the variable
+	Ident       *ast.Ident   // Ident is the first reference to the captured variable in the closure
+	ByReference bool         // ByReference is true if the closure may run after the variable changes
+	InLoop      bool         // InLoop is true if the closure is created in a loop
+}
+
+// isScopeWithin checks whether the scope is the outer scope itself or nested in it.
+func isScopeWithin(scope, outer *types.Scope) bool {
+	for ; scope != nil; scope = scope.Parent() {
+		if scope == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// escapesFrom checks whether the closure at the top of stack may be run after the statement creating
+// it, i.e. it's not called in place, or it's called by the go or defer statement.
+func escapesFrom(stack []ast.Node) bool {
+	if len(stack) < 2 {
+		return true
+	}
+	call, ok := stack[len(stack)-2].(*ast.CallExpr)
+	if !ok || call.Fun != stack[len(stack)-1] {
+		return true // returned, assigned or passed as argument
+	}
+	if len(stack) < 3 {
+		return false
+	}
+	switch stack[len(stack)-3].(type) {
+	case *ast.GoStmt, *ast.DeferStmt:
+		return true
+	}
+	return false
+}
+
+// ClosureCaptures finds the local variables captured by closures in the source file, i.e. those used
+// in a function literal but declared in its enclosing functions (resolved by typInfo.Scopes). Since
+// the closures share the variables rather than copy them, the captures are flagged ByReference if
+// the closure escapes (runs by go or defer, or is stored elsewhere). It returns nil if type info
+// isn't loaded.
+func (file *SrcFile) ClosureCaptures() []CaptureDiagnostic {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var info = file.pkg.typInfo
+	var diagnostics []CaptureDiagnostic
+	inspectWithStack(file.syntax, func(node ast.Node, stack []ast.Node) bool {
+		funcLit, ok := node.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		litScope := info.Scopes[funcLit.Type]
+		if litScope == nil {
+			return true
+		}
+		var escapes = escapesFrom(stack)
+		var isInLoop = inLoop(stack[:len(stack)-1])
+		var captured = make(map[types.Object]bool)
+		ast.Inspect(funcLit.Body, func(node ast.Node) bool {
+			ident, ok := node.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			variable, ok := info.Uses[ident].(*types.Var)
+			if !ok || variable.IsField() || variable.Parent() == nil || captured[variable] {
+				return true
+			}
+			if variable.Pkg() != nil && variable.Parent() == variable.Pkg().Scope() {
+				return true // package-level variables are not captured
+			}
+			if isScopeWithin(variable.Parent(), litScope) {
+				return true // declared in the closure itself
+			}
+			captured[variable] = true
+			diagnostics = append(diagnostics, CaptureDiagnostic{
+				Closure:     funcLit,
+				Ident:       ident,
+				ByReference: escapes,
+				InLoop:      isInLoop,
+			})
+			return true
+		})
+		return true
+	})
+	return diagnostics
+}
+
+// isBuiltinCall checks whether the call invokes the built-in function with the name.
+func isBuiltinCall(call *ast.CallExpr, info *types.Info, name string) bool {
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	builtin, ok := info.Uses[ident].(*types.Builtin)
+	return ok && builtin.Name() == name
+}
+
+// builtinSitesOf returns the positions of calls of the built-in function with name in source file,
+// or nil if type info isn't loaded.
+func (file *SrcFile) builtinSitesOf(name string) []token.Position {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var positions []token.Position
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok && isBuiltinCall(call, file.pkg.typInfo, name) {
+			positions = append(positions, file.pkg.fileSet.Position(call.Pos()))
+		}
+		return true
+	})
+	return positions
+}
+
+// PanicSites returns the positions of calls of the built-in panic in the source file, or nil if type
+// info isn't loaded.
+func (file *SrcFile) PanicSites() []token.Position {
+	return file.builtinSitesOf("panic")
+}
+
+// RecoverSites returns the positions of calls of the built-in recover in the source file, or nil if
+// type info isn't loaded.
+func (file *SrcFile) RecoverSites() []token.Position {
+	return file.builtinSitesOf("recover")
+}
+
+// UnrecoveredPanics returns the functions in the package that call panic in their bodies, but have
+// no defer statement calling recover. The function literals in the bodies are not inspected, since
+// they are run in the other frames (e.g. goroutines). It returns nil if type info isn't loaded.
+func (pkg *Package) UnrecoveredPanics() []*ast.FuncDecl {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var info = pkg.typInfo
+	var functions []*ast.FuncDecl
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, funcDecl := range srcFile.Functions() {
+			if funcDecl.Body == nil {
+				continue
+			}
+			var panics, recovers bool
+			ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+				switch stmt := node.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.CallExpr:
+					panics = panics || isBuiltinCall(stmt, info, "panic")
+				case *ast.DeferStmt:
+					ast.Inspect(stmt.Call, func(node ast.Node) bool {
+						if call, ok := node.(*ast.CallExpr); ok && isBuiltinCall(call, info, "recover") {
+							recovers = true
+						}
+						return !recovers
+					})
+				}
+				return true
+			})
+			if panics && !recovers {
+				functions = append(functions, funcDecl)
+			}
+		}
+	}
+	return functions
+}
+
+// UnusedParamDiagnostic is a named parameter of function that is never referenced in its body.
+type UnusedParamDiagnostic struct {
+	Func  *ast.FuncDecl  // Func is the function declaring the parameter
+	Param string         // Param is the name of unused parameter
+	Pos   token.Position // Pos is the position of parameter's name in source file
+}
+
+// interfacesOf returns the interfaces declared at the package level of the package and its imports,
+// which the methods declared in the package might implement.
+func interfacesOf(typePkg *types.Package) []*types.Interface {
+	var interfaces []*types.Interface
+	var scopes = []*types.Scope{typePkg.Scope()}
+	for _, imported := range typePkg.Imports() {
+		scopes = append(scopes, imported.Scope())
+	}
+	for _, scope := range scopes {
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !typeName.Exported() && typeName.Pkg() != typePkg {
+				continue
+			}
+			if iface, ok := typeName.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+				interfaces = append(interfaces, iface)
+			}
+		}
+	}
+	return interfaces
+}
+
+// implementsInterface checks whether the method implements a method of any interface, i.e. the
+// interface has a method of the same name, and the receiver type (or its pointer) implements it.
+func implementsInterface(method *types.Func, interfaces []*types.Interface) bool {
+	signature, ok := method.Type().(*types.Signature)
+	if !ok || signature.Recv() == nil {
+		return false
+	}
+	var recvType = signature.Recv().Type()
+	if pointer, ok := recvType.(*types.Pointer); ok {
+		recvType = pointer.Elem()
+	}
+	for _, iface := range interfaces {
+		for i := 0; i < iface.NumMethods(); i++ {
+			if iface.Method(i).Name() != method.Name() {
+				continue
+			}
+			if types.Implements(recvType, iface) || types.Implements(types.NewPointer(recvType), iface) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnusedParams finds the named parameters of functions in the source file that are never used in
+// their bodies (resolved by typInfo.Uses), except the blank `_`. The functions without bodies and
+// the methods implementing interfaces (declared in the package or its imports) are excluded, since
+// their signatures are fixed. It returns nil if type info isn't loaded.
+func (file *SrcFile) UnusedParams() []UnusedParamDiagnostic {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil ||
+		file.pkg.typePkg == nil {
+		return nil
+	}
+	var info = file.pkg.typInfo
+	var interfaces []*types.Interface // lazily collected once any method has unused params
+	var diagnostics []UnusedParamDiagnostic
+	for _, funcDecl := range file.Functions() {
+		if funcDecl.Body == nil || funcDecl.Type.Params == nil {
+			continue
+		}
+
+		// 1. collect the parameters and their usages in body
+		var params []*ast.Ident
+		var usages = make(map[types.Object]int)
+		for _, field := range funcDecl.Type.Params.List {
+			for _, name := range field.Names {
+				if object := info.Defs[name]; object != nil && name.Name != "_" {
+					params = append(params, name)
+					usages[object] = 0
+				}
+			}
+		}
+		if len(params) == 0 {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok {
+				if _, ok := usages[info.Uses[ident]]; ok {
+					usages[info.Uses[ident]]++
+				}
+			}
+			return true
+		})
+
+		// 2. report the unused ones unless implementing interface
+		var unused []*ast.Ident
+		for _, param := range params {
+			if usages[info.Defs[param]] == 0 {
+				unused = append(unused, param)
+			}
+		}
+		if len(unused) == 0 {
+			continue
+		}
+		if method, ok := info.Defs[funcDecl.Name].(*types.Func); ok && funcDecl.Recv != nil {
+			if interfaces == nil {
+				interfaces = interfacesOf(file.pkg.typePkg)
+			}
+			if implementsInterface(method, interfaces) {
+				continue
+			}
+		}
+		for _, param := range unused {
+			diagnostics = append(diagnostics, UnusedParamDiagnostic{
+				Func:  funcDecl,
+				Param: param.Name,
+				Pos:   file.pkg.fileSet.Position(param.Pos()),
+			})
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,429 @@
+package golang
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestContextPropagation(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "context"
+
+func do(ctx context.Context, n int) error { return ctx.Err() }
+
+func Passes(ctx context.Context) error { return do(ctx, 1) }
+
+func Derives(ctx context.Context) error {
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	return do(child, 1)
+}
+
+func Drops(ctx context.Context) error {
+	if err := do(context.Background(), 1); err != nil {
+		return err
+	}
+	return do(context.TODO(), 2)
+}
+
+func NoContext() error { return do(context.Background(), 1) }
+`})
+	var diagnostics = pkg.SrcFile("p.go").ContextPropagation()
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.Func.Name.Name+":"+diagnostic.Callee+"@"+diagnostic.Pos.String())
+	}
+	var want = []string{"Drops:do@p.go:16:12", "Drops:do@p.go:19:9"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ContextPropagation = %v, want %v", got, want)
+	}
+}
+
+func TestStringConcatPatterns(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type T struct{ s string }
+
+func Join(items []string) string {
+	var s string
+	for _, item := range items {
+		s += item
+	}
+	return s
+}
+
+func (t *T) Repeat(n int) {
+	for i := 0; i < n; i++ {
+		t.s = t.s + "x" + "y"
+	}
+}
+
+func Once(a, b string) string {
+	s := a
+	s += b
+	return s
+}
+
+func Sum(n int) int {
+	var total int
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	return total
+}
+
+func Prefix(items []string) {
+	var s string
+	for _, item := range items {
+		s = item + s
+	}
+	_ = s
+}
+`})
+	var got []string
+	for _, diagnostic := range pkg.StringConcatPatterns() {
+		got = append(got, diagnostic.Func+"@"+diagnostic.Pos.String())
+	}
+	var want = []string{"Join@p.go:8:3", "(*T).Repeat@p.go:15:3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StringConcatPatterns = %v, want %v", got, want)
+	}
+}
+
+func TestErrorReturns(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "strconv"
+
+type MyErr struct{}
+
+func (*MyErr) Error() string { return "" }
+
+func Parse(s string) (int, error) { return strconv.Atoi(s) }
+
+func Custom() *MyErr { return nil }
+
+func Plain() int { return 0 }
+
+func Ignore() int {
+	n, _ := Parse("1")
+	var m, _ = strconv.Atoi("2")
+	_, err := Parse("3")
+	_ = err
+	_, _ = Plain(), Plain()
+	return n + m
+}
+`})
+	srcFile := pkg.SrcFile("p.go")
+	var names []string
+	for _, funcDecl := range srcFile.ErrorReturns() {
+		names = append(names, funcNameOf(funcDecl))
+	}
+	if want := []string{"Parse", "Custom"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ErrorReturns = %v, want %v", names, want)
+	}
+	var positions []string
+	for _, position := range srcFile.IgnoredErrors() {
+		positions = append(positions, position.String())
+	}
+	if want := []string{"p.go:16:10", "p.go:17:13"}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("IgnoredErrors = %v, want %v", positions, want)
+	}
+}
+
+func TestErrorWrapping(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "fmt"
+
+type local struct{}
+
+func (local) Errorf(format string, args ...any) error { return nil }
+
+func Lost(err error) error { return fmt.Errorf("lost: %v", err) }
+
+func Wrapped(err error) error { return fmt.Errorf("wrapped: %w", err) }
+
+func NoError(n int) error { return fmt.Errorf("bad %d", n) }
+
+func Shadowed(err error) error {
+	var fmt local
+	return fmt.Errorf("shadowed: %v", err)
+}
+`})
+	var got []string
+	for _, diagnostic := range pkg.ErrorWrapping() {
+		got = append(got, diagnostic.Func+":"+diagnostic.Format+"@"+diagnostic.Pos.String())
+	}
+	if want := []string{"Lost:lost: %v@p.go:9:37"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ErrorWrapping = %v, want %v", got, want)
+	}
+}
+
+func TestTypeAssertions(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "fmt"
+
+func Assert(x any) {
+	s := x.(string)
+	n, ok := x.(int)
+	var e, isErr = x.(error)
+	_ = x.(fmt.Stringer).String()
+	switch x.(type) {
+	case bool:
+	}
+	_, _, _, _, _ = s, n, ok, e, isErr
+}
+`})
+	var got []string
+	for _, assertion := range pkg.SrcFile("p.go").TypeAssertions() {
+		got = append(got, fmt.Sprintf("%s:%v@%s", assertion.Type, assertion.Safe, assertion.Pos))
+	}
+	var want = []string{"string:false@p.go:6:7", "int:true@p.go:7:11", "error:true@p.go:8:17", "fmt.Stringer:false@p.go:9:6"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeAssertions = %v, want %v", got, want)
+	}
+}
+
+func TestRegexpCompileOutsideFunc(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "regexp"
+
+var word = regexp.MustCompile("[a-z]+")
+
+var digits *regexp.Regexp
+
+func init() { digits = regexp.MustCompile("[0-9]+") }
+
+type T struct{}
+
+func (T) Match(s string) bool { return regexp.MustCompile("^t").MatchString(s) }
+
+func Dynamic(pattern string) (*regexp.Regexp, error) { return regexp.Compile(pattern) }
+
+func Posix() (*regexp.Regexp, error) { return regexp.CompilePOSIX("a|b") }
+
+func Inner() func() { return func() { regexp.MustCompile("x") } }
+`})
+	var got []string
+	for _, diagnostic := range pkg.SrcFile("p.go").RegexpCompileOutsideFunc() {
+		got = append(got, diagnostic.Func+"@"+diagnostic.Pos.String())
+	}
+	var want = []string{"T.Match@p.go:13:40", "Posix@p.go:17:47", "Inner@p.go:19:39"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RegexpCompileOutsideFunc = %v, want %v", got, want)
+	}
+}
+
+func TestNilChecks(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "io"
+
+type T struct{ n int }
+
+func Unguarded(t *T, r io.Reader) int {
+	_, _ = r.Read(nil)
+	return t.n + t.n
+}
+
+func Returned(t *T) int {
+	if t == nil {
+		return 0
+	}
+	return t.n
+}
+
+func Nested(t *T) int {
+	if t != nil {
+		return t.n
+	}
+	return 0
+}
+
+func Chained(t *T) bool { return t != nil && t.n > 0 }
+
+func Value(t T) int { return t.n }
+
+func (t *T) Receiver() int { return t.n }
+`})
+	var got []string
+	for _, diagnostic := range pkg.NilChecks() {
+		got = append(got, diagnostic.Func+":"+diagnostic.Var+"@"+diagnostic.Pos.String())
+	}
+	var want = []string{"Unguarded:r@p.go:8:9", "Unguarded:t@p.go:9:9"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NilChecks = %v, want %v", got, want)
+	}
+}
+
+func TestConsistentReceivers(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type Mixed struct{}
+
+func (*Mixed) Set() {}
+
+func (Mixed) Get() int { return 0 }
+
+type Stringer struct{}
+
+func (*Stringer) Set() {}
+
+func (Stringer) String() string { return "" }
+
+type Pointers struct{}
+
+func (*Pointers) A() {}
+
+func (*Pointers) B() {}
+
+type Level int
+
+func (*Level) Set() {}
+
+func (Level) Get() int { return 0 }
+`})
+	var got []string
+	for _, diagnostic := range pkg.ConsistentReceivers() {
+		var methods []string
+		for _, method := range diagnostic.PointerMethods {
+			methods = append(methods, "*"+method.Name+"@"+method.Pos.String())
+		}
+		for _, method := range diagnostic.ValueMethods {
+			methods = append(methods, method.Name+"@"+method.Pos.String())
+		}
+		got = append(got, fmt.Sprintf("%s%v", diagnostic.Type, methods))
+	}
+	if want := []string{"Mixed[*Set@p.go:5:15 Get@p.go:7:14]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConsistentReceivers = %v, want %v", got, want)
+	}
+}
+
+func TestClosureCaptures(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+var global int
+
+func Captures(items []int) func() int {
+	total := 0
+	func() { total += global }()
+	for _, item := range items {
+		go func() { total += item }()
+		defer func(n int) { _ = n }(item)
+	}
+	return func() int {
+		local := total
+		return local
+	}
+}
+`})
+	srcFile := pkg.SrcFile("p.go")
+	var got []string
+	for _, capture := range srcFile.ClosureCaptures() {
+		line, _, _ := srcFile.LineColumn(capture.Ident.Pos())
+		got = append(got, fmt.Sprintf("%s@%d:%v:%v", capture.Ident.Name, line, capture.ByReference, capture.InLoop))
+	}
+	var want = []string{"total@7:false:false", "total@9:true:true", "item@9:true:true", "total@13:true:false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClosureCaptures = %v, want %v", got, want)
+	}
+}
+
+func TestPanicAndRecoverSites(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+func Unrecovered(n int) {
+	if n < 0 {
+		panic("negative")
+	}
+}
+
+func Recovered() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = nil
+		}
+	}()
+	panic("recovered")
+}
+
+func InClosure() func() {
+	return func() { panic("in closure") }
+}
+
+func shadowed() {
+	panic := func(string) {}
+	panic("not builtin")
+}
+`})
+	srcFile := pkg.SrcFile("p.go")
+	var positionsOf = func(positions []token.Position) []string {
+		var got []string
+		for _, position := range positions {
+			got = append(got, position.String())
+		}
+		return got
+	}
+	if got, want := positionsOf(srcFile.PanicSites()), []string{"p.go:5:3", "p.go:15:2", "p.go:19:18"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PanicSites = %v, want %v", got, want)
+	}
+	if got, want := positionsOf(srcFile.RecoverSites()), []string{"p.go:11:11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecoverSites = %v, want %v", got, want)
+	}
+	var names []string
+	for _, funcDecl := range pkg.UnrecoveredPanics() {
+		names = append(names, funcDecl.Name.Name)
+	}
+	if want := []string{"Unrecovered"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("UnrecoveredPanics = %v, want %v", names, want)
+	}
+}
+
+func TestSrcFileUnusedParams(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "io"
+
+type T struct{}
+
+func (T) Read(p []byte) (int, error) { return 0, nil }
+
+type Runner interface{ Run(ctx int) }
+
+func (T) Run(ctx int) {}
+
+func (T) Other(x int) {}
+
+func Use(a, b int, _ string) int {
+	return a
+}
+
+func Shadow(x int) int {
+	{
+		x := 2
+		return x
+	}
+}
+
+func Closure(y int) func() int { return func() int { return y } }
+
+var _ io.Reader = T{}
+`})
+	var got []string
+	for _, diagnostic := range pkg.SrcFile("p.go").UnusedParams() {
+		got = append(got, fmt.Sprintf("%s.%s@%d:%d", diagnostic.Func.Name.Name, diagnostic.Param,
+			diagnostic.Pos.Line, diagnostic.Pos.Column))
+	}
+	if want := []string{"Other.x@13:16", "Use.b@15:13", "Shadow.x@19:13"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnusedParams = %v, want %v", got, want)
+	}
+	if got := newSrcFile(nil, "p.go").UnusedParams(); got != nil {
+		t.Errorf("UnusedParams without type info = %v, want nil", got)
+	}
+}
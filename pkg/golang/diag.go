@@ -0,0 +1,132 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file defines Diagnostic, which represents a finding reported by the analyzers
+// taking Package as input, along with its position, category, severity and the suggested fixes.
+package golang
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+)
+
+// Severity is the level of a Diagnostic, which decides how the finding is treated by the reports.
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // SeverityInfo is for the findings as suggestions
+	SeverityWarning                 // SeverityWarning is for the suspicious code to be reviewed
+	SeverityError                   // SeverityError is for the code that is certainly wrong
+)
+
+// String returns the name of severity in lower case, i.e. "info", "warning" or "error".
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(severity))
+	}
+}
+
+// Diagnostic is a finding reported by the analyzer in the range of source code [Pos, End), where
+// the positions are resolved by the FileSet of Package being analyzed.
+type Diagnostic struct {
+	Pos      token.Pos // Pos is the start position of the code being reported
+	End      token.Pos // End is the end position of the code, or token.NoPos if unknown
+	Category string    // Category is the name of check or analyzer reporting the finding
+	Message  string    // Message is the human-readable description of the finding
+	Severity Severity  // Severity is the level of the finding
+
+	SuggestedFixes []SuggestedFix // SuggestedFixes are the alternative fixes of the finding
+}
+
+// TextEdit replaces the source code in range [Pos, End) with NewText, which inserts the text if Pos
+// equals End, or deletes the range if NewText is empty.
+type TextEdit struct {
+	Pos     token.Pos // Pos is the start position of the code being replaced
+	End     token.Pos // End is the end position of the code being replaced
+	NewText []byte    // NewText is the text to replace the code in range
+}
+
+// SuggestedFix is a fix of a Diagnostic, which consists of the edits to apply together.
+type SuggestedFix struct {
+	Message   string     // Message describes the fix, e.g. "replace x with y"
+	TextEdits []TextEdit // TextEdits are the edits of fix which shall not overlap
+}
+
+// Render renders the diagnostic as `file:line:col: message` by the positions in the FileSet, or as
+// `-: message` if the position is invalid or not in the FileSet.
+func (diag Diagnostic) Render(fileSet *token.FileSet) string {
+	if fileSet == nil || !diag.Pos.IsValid() || fileSet.File(diag.Pos) == nil {
+		return fmt.Sprintf("-: %s", diag.Message)
+	}
+	position := fileSet.Position(diag.Pos)
+	return fmt.Sprintf("%s:%d:%d: %s", position.Filename, position.Line, position.Column, diag.Message)
+}
+
+// ApplyFixes applies the edits of all fixes to the code of the source file and returns the new code,
+// where the edits are applied in reverse order of positions such that the offsets of those before
+// remain valid. It returns error if any edit is out of the file or two edits overlap, where an
+// edit with invalid End is taken as an insertion at Pos.
+func ApplyFixes(file *SrcFile, fixes []SuggestedFix) ([]byte, error) {
+	// 1. validate the source file and collect edits
+	if file == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return nil, fmt.Errorf("nil source file is used")
+	}
+	type offsetEdit struct {
+		begin, end int    // begin and end are the byte offsets of range in code
+		newText    []byte // newText is the text to replace the code in range
+	}
+	var edits []offsetEdit
+	for _, fix := range fixes {
+		for _, edit := range fix.TextEdits {
+			var end = edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			_, _, begOk := file.LineColumn(edit.Pos)
+			_, _, endOk := file.LineColumn(end)
+			if !begOk || !endOk {
+				return nil, fmt.Errorf("edit out of file: %s", file.path)
+			}
+			begin := file.pkg.fileSet.Position(edit.Pos).Offset
+			offset := file.pkg.fileSet.Position(end).Offset
+			if begin > offset || offset > len(file.code) {
+				return nil, fmt.Errorf("invalid edit range: %s", file.path)
+			}
+			edits = append(edits, offsetEdit{begin: begin, end: offset, newText: edit.NewText})
+		}
+	}
+
+	// 2. sort edits in reverse order and check overlaps
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].begin != edits[j].begin {
+			return edits[i].begin > edits[j].begin
+		}
+		return edits[i].end > edits[j].end
+	})
+	for i := 1; i < len(edits); i++ {
+		// two insertions at the same offset are ambiguous in order
+		if edits[i].end > edits[i-1].begin ||
+			(edits[i].begin == edits[i-1].begin && edits[i-1].begin == edits[i-1].end) {
+			return nil, fmt.Errorf("overlapped edits at offset %d: %s", edits[i-1].begin, file.path)
+		}
+	}
+
+	// 3. apply the edits from the end of code to begin
+	var code = []byte(file.code)
+	for _, edit := range edits {
+		var newCode = make([]byte, 0, len(code)-(edit.end-edit.begin)+len(edit.newText))
+		newCode = append(newCode, code[:edit.begin]...)
+		newCode = append(newCode, edit.newText...)
+		newCode = append(newCode, code[edit.end:]...)
+		code = newCode
+	}
+	return code, nil
+}
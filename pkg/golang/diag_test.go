@@ -0,0 +1,81 @@
+package golang
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticRender(t *testing.T) {
+	const code = "package p\n\nfunc F() {\n\tvar x int\n\t_ = x\n}\n"
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": code})
+	var offset = pkg.FileSet().File(pkg.SrcFile("p.go").Syntax().Pos()).Base() + strings.Index(code, "x int")
+	var diag = Diagnostic{
+		Pos:      token.Pos(offset),
+		End:      token.Pos(offset + 1),
+		Category: "shadow",
+		Message:  "x is declared",
+		Severity: SeverityWarning,
+	}
+	if got, want := diag.Render(pkg.FileSet()), "p.go:4:6: x is declared"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	if got, want := diag.Render(token.NewFileSet()), "-: x is declared"; got != want {
+		t.Errorf("Render(another FileSet) = %q, want %q", got, want)
+	}
+	diag.Pos = token.NoPos
+	if got, want := diag.Render(pkg.FileSet()), "-: x is declared"; got != want {
+		t.Errorf("Render(NoPos) = %q, want %q", got, want)
+	}
+	for severity, want := range map[Severity]string{
+		SeverityInfo: "info", SeverityWarning: "warning", SeverityError: "error", Severity(7): "severity(7)",
+	} {
+		if got := severity.String(); got != want {
+			t.Errorf("Severity(%d) = %q, want %q", int(severity), got, want)
+		}
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	const code = "package p\n\nfunc F() {\n\tvar x int\n\t_ = x\n}\n"
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": code})
+	var srcFile = pkg.SrcFile("p.go")
+	var base = pkg.FileSet().File(srcFile.Syntax().Pos()).Base()
+	var rename = func(offset int, newText string) TextEdit {
+		return TextEdit{Pos: token.Pos(base + offset), End: token.Pos(base + offset + 1), NewText: []byte(newText)}
+	}
+	var declared, used = strings.Index(code, "x int"), strings.LastIndex(code, "x")
+
+	fixed, err := ApplyFixes(srcFile, []SuggestedFix{
+		{Message: "rename x to y", TextEdits: []TextEdit{rename(used, "y"), rename(declared, "y")}},
+		{Message: "insert comment", TextEdits: []TextEdit{{Pos: token.Pos(base + len(code)), NewText: []byte("// end\n")}}},
+	})
+	if want := "package p\n\nfunc F() {\n\tvar y int\n\t_ = y\n}\n// end\n"; err != nil || string(fixed) != want {
+		t.Errorf("ApplyFixes = %q, %v, want %q", fixed, err, want)
+	}
+	if srcFile.Code() != code {
+		t.Errorf("ApplyFixes changes the code of source file")
+	}
+
+	for name, fixes := range map[string][]SuggestedFix{
+		"overlapped": {
+			{Message: "rename x", TextEdits: []TextEdit{rename(declared, "y")}},
+			{Message: "rename x int", TextEdits: []TextEdit{{Pos: token.Pos(base + declared),
+				End: token.Pos(base + declared + len("x int")), NewText: []byte("y bool")}}},
+		},
+		"same insertions": {
+			{Message: "insert a", TextEdits: []TextEdit{{Pos: token.Pos(base + declared), NewText: []byte("a")}}},
+			{Message: "insert b", TextEdits: []TextEdit{{Pos: token.Pos(base + declared), NewText: []byte("b")}}},
+		},
+		"out of file": {
+			{Message: "rename", TextEdits: []TextEdit{{Pos: token.NoPos, End: token.NoPos}}},
+		},
+	} {
+		if fixed, err := ApplyFixes(srcFile, fixes); err == nil {
+			t.Errorf("%s: ApplyFixes = %q, want error", name, fixed)
+		}
+	}
+	if _, err := ApplyFixes(nil, nil); err == nil {
+		t.Errorf("ApplyFixes(nil) = nil, want error")
+	}
+}
@@ -6,9 +6,16 @@
 package golang
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"go/ast"
+	"go/parser"
 	"go/token"
+	"go/types"
+	"os"
+	"strings"
 
 	"golang.org/x/tools/go/ssa"
 )
@@ -26,6 +33,10 @@ type SrcFile struct {
 	code   string       // code is the text in the source file being analyzed
 	syntax *ast.File    // syntax is the abstract syntax tree of source file (AST)
 	memSet []ssa.Member // memSet are the static single assignment (SSA) members in the file
+
+	checksum [16]byte // checksum is the MD5 digest of code, computed when the file is updated
+	hash     string   // hash is the hex SHA-256 of code, computed lazily or empty if not yet
+	lines    []string // lines are the code split into lines, computed lazily or nil if not yet
 }
 
 // newSrcFile is an internal method that ONLY be invoked by Package
@@ -36,6 +47,10 @@ func newSrcFile(pkg *Package, path string) *SrcFile {
 		code:   "",
 		syntax: nil,
 		memSet: nil,
+
+		checksum: md5.Sum(nil),
+		hash:     "",
+		lines:    nil,
 	}
 }
 
@@ -55,6 +70,20 @@ func (file *SrcFile) Path() string {
 	return ""
 }
 
+// IsTest checks whether this is a test file, i.e. its path ends with "_test.go".
+func (file *SrcFile) IsTest() bool {
+	return file != nil && strings.HasSuffix(file.path, "_test"+GoFileSuffix)
+}
+
+// IsExternalTest checks whether this is a test file declaring the external test package, of which
+// the name (in the parsed package clause) ends with "_test". It returns false if syntax isn't loaded.
+func (file *SrcFile) IsExternalTest() bool {
+	if !file.IsTest() || file.syntax == nil || file.syntax.Name == nil {
+		return false
+	}
+	return strings.HasSuffix(file.syntax.Name.Name, "_test")
+}
+
 // Code is the text in the source file being analyzed
 func (file *SrcFile) Code() string {
 	if file != nil {
@@ -79,6 +108,141 @@ func (file *SrcFile) Members() []ssa.Member {
 	return nil
 }
 
+// ControlFlowGraph maps the name of each SSA function in the file to its ssa.Function, of which the
+// Blocks form the control flow graph. The methods are named with their receivers as "T.M" or "(*T).M"
+// (as SSAFunc accepts), including those of types declared in other files. It returns nil if the SSA
+// form is not built, thus the caller must load the package with SSA (via BuildSSA) before using it.
+func (file *SrcFile) ControlFlowGraph() map[string]*ssa.Function {
+	if file == nil || file.pkg == nil || file.pkg.ssaPkg == nil {
+		return nil
+	}
+	var functions = make(map[string]*ssa.Function)
+	for _, member := range file.pkg.ssaPkg.Members {
+		switch member := member.(type) {
+		case *ssa.Function:
+			if file.Contain(member.Pos()) {
+				functions[member.Name()] = member
+			}
+		case *ssa.Type:
+			named, ok := member.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			for i := 0; i < named.NumMethods(); i++ {
+				method := named.Method(i)
+				function := file.pkg.ssaPkg.Prog.FuncValue(method)
+				if function == nil || !file.Contain(function.Pos()) {
+					continue
+				}
+				var funcName = fmt.Sprintf("%s.%s", named.Obj().Name(), method.Name())
+				signature, _ := method.Type().(*types.Signature)
+				if signature != nil && signature.Recv() != nil {
+					if _, isPointer := signature.Recv().Type().(*types.Pointer); isPointer {
+						funcName = fmt.Sprintf("(*%s).%s", named.Obj().Name(), method.Name())
+					}
+				}
+				functions[funcName] = function
+			}
+		}
+	}
+	return functions
+}
+
+// Checksum is the MD5 digest of the code in this source file when it was loaded.
+func (file *SrcFile) Checksum() [16]byte {
+	if file != nil {
+		return file.checksum
+	}
+	return [16]byte{}
+}
+
+// Hash is the hex-encoded SHA-256 of the code in this source file, which is computed at the first
+// call and cached until the file is updated, such that it can be used as the key of result caches.
+func (file *SrcFile) Hash() string {
+	if file == nil {
+		return ""
+	}
+	if len(file.hash) == 0 {
+		digest := sha256.Sum256([]byte(file.code))
+		file.hash = hex.EncodeToString(digest[:])
+	}
+	return file.hash
+}
+
+// IsStale checks whether the file on disk has been changed since its code was loaded, by comparing
+// the MD5 digest of the file on disk with the cached checksum. It returns error if it can't be read.
+func (file *SrcFile) IsStale() (bool, error) {
+	if file == nil {
+		return false, fmt.Errorf("nil file is used")
+	}
+	bytes, err := os.ReadFile(file.path)
+	if err != nil {
+		return false, err
+	}
+	return md5.Sum(bytes) != file.checksum, nil
+}
+
+// readAndParse reads the code of this file from disk and parses its syntax tree by the FileSet of
+// package, without updating this file.
+func (file *SrcFile) readAndParse() ([]byte, *ast.File, error) {
+	if file == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return nil, nil, fmt.Errorf("file not loaded in package")
+	}
+	bytes, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	syntax, err := parser.ParseFile(file.pkg.fileSet, file.path, bytes, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes, syntax, nil
+}
+
+// Reload reads the code of this file from disk again and updates its syntax tree, while the types
+// of package are not checked again (use Package.Reload), so the type info of this file is stale.
+func (file *SrcFile) Reload() error {
+	bytes, syntax, err := file.readAndParse()
+	if err != nil {
+		return err
+	}
+	return file.update(string(bytes), syntax, nil)
+}
+
+// Lines returns the lines of code in this source file without the line breaks, where the empty line
+// after the final new line is excluded, such that the n-th line (1-based) is Lines()[n-1].
+func (file *SrcFile) Lines() []string {
+	if file == nil {
+		return nil
+	}
+	if file.lines == nil {
+		file.lines = strings.Split(strings.TrimSuffix(file.code, NewLine), NewLine)
+	}
+	return file.lines
+}
+
+// Line returns the n-th line (1-based, as token.Position.Line) of code in this source file, or error
+// if it is out of range.
+func (file *SrcFile) Line(n int) (string, error) {
+	var lines = file.Lines()
+	if n < 1 || n > len(lines) {
+		return "", fmt.Errorf("line %d out of range [1, %d]", n, len(lines))
+	}
+	return lines[n-1], nil
+}
+
+// LinesInRange returns the lines of code in this source file from the line of start to the line of
+// end (both included), or nil if either position is invalid or not in this file.
+func (file *SrcFile) LinesInRange(start, end token.Pos) []string {
+	begLine, _, begOk := file.LineColumn(start)
+	endLine, _, endOk := file.LineColumn(end)
+	var lines = file.Lines()
+	if !begOk || !endOk || begLine > endLine || endLine > len(lines) {
+		return nil
+	}
+	return lines[begLine-1 : endLine]
+}
+
 // Contain checks whether the position is included by this source file.
 func (file *SrcFile) Contain(pos token.Pos) bool {
 	if file != nil && pos.IsValid() {
@@ -88,10 +252,26 @@ func (file *SrcFile) Contain(pos token.Pos) bool {
 	return false
 }
 
+// LineColumn returns the 1-based line and column of the position in this source file, or false if
+// the position is invalid or belongs to another file.
+func (file *SrcFile) LineColumn(pos token.Pos) (line, col int, ok bool) {
+	if file == nil || file.pkg == nil || file.pkg.fileSet == nil || !pos.IsValid() {
+		return 0, 0, false
+	}
+	position := file.pkg.fileSet.Position(pos)
+	if position.Filename != file.path || !position.IsValid() {
+		return 0, 0, false
+	}
+	return position.Line, position.Column, true
+}
+
 // update will reset the syntax, type and semantic information of the source file.
 func (file *SrcFile) update(code string, syntax *ast.File, members map[string]ssa.Member) error {
 	if file != nil {
 		file.code = code
+		file.checksum = md5.Sum([]byte(code))
+		file.hash = ""
+		file.lines = nil
 		file.syntax = syntax
 		file.memSet = nil
 		if members != nil && len(members) > 0 {
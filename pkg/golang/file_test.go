@@ -0,0 +1,193 @@
+package golang
+
+import (
+	"crypto/md5"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestControlFlowGraph(t *testing.T) {
+	pkg, err := NewVirtualPackage("p", "example.com/p", map[string]string{
+		"a.go": "package p\n\ntype T struct{}\n\nfunc (T) M() {}\n\nfunc (*T) P() {}\n\nfunc F() {}\n",
+		"b.go": "package p\n\nfunc (T) N(x int) int {\n\tif x > 0 {\n\t\treturn x\n\t}\n\treturn -x\n}\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pkg.SrcFile("a.go").ControlFlowGraph(); got != nil {
+		t.Errorf("ControlFlowGraph before BuildSSA = %v, want nil", got)
+	}
+	if err := pkg.BuildSSA(); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		file  string
+		names []string
+	}{
+		{"a.go", []string{"(*T).P", "F", "T.M"}},
+		{"b.go", []string{"T.N"}},
+	} {
+		var graph = pkg.SrcFile(test.file).ControlFlowGraph()
+		var names []string
+		for name, function := range graph {
+			names = append(names, name)
+			if len(function.Blocks) == 0 {
+				t.Errorf("%s: %s has no blocks", test.file, name)
+			}
+			if fn, err := pkg.SSAFunc(name); err != nil || fn != function {
+				t.Errorf("%s: SSAFunc(%q) = %v, %v, want %v", test.file, name, fn, err, function)
+			}
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: functions = %v, want %v", test.file, names, test.names)
+		}
+	}
+}
+
+func TestSrcFileIsStale(t *testing.T) {
+	const code = "package p\n\nconst N = 1\n"
+	pkg, path := reloadFixture(t, code)
+	srcFile := pkg.SrcFile(path)
+	if srcFile.Checksum() != md5.Sum([]byte(code)) {
+		t.Errorf("Checksum = %x, want the MD5 of code", srcFile.Checksum())
+	}
+	if stale, err := srcFile.IsStale(); err != nil || stale {
+		t.Errorf("IsStale of unchanged file = %v, %v, want false", stale, err)
+	}
+
+	const changed = "package p\n\nconst N = 2\n"
+	rewrite(t, path, changed)
+	if stale, err := srcFile.IsStale(); err != nil || !stale {
+		t.Errorf("IsStale of changed file = %v, %v, want true", stale, err)
+	}
+	if err := srcFile.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Checksum() != md5.Sum([]byte(changed)) {
+		t.Errorf("Checksum after Reload = %x, want the MD5 of changed code", srcFile.Checksum())
+	}
+	if stale, err := srcFile.IsStale(); err != nil || stale {
+		t.Errorf("IsStale after Reload = %v, %v, want false", stale, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srcFile.IsStale(); err == nil {
+		t.Errorf("IsStale of removed file = nil, want error")
+	}
+}
+
+func TestSrcFileLineColumn(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nvar A = 1\n",
+		"b.go": "package p\n\nvar B = 2\n",
+	})
+	var a, b = pkg.SrcFile("a.go"), pkg.SrcFile("b.go")
+	var bPos = b.Syntax().Decls[0].Pos()
+	if line, col, ok := b.LineColumn(bPos + 4); !ok || line != 3 || col != 5 {
+		t.Errorf("LineColumn(B) = %d:%d, %v, want 3:5", line, col, ok)
+	}
+	if line, col, ok := a.LineColumn(bPos); ok {
+		t.Errorf("LineColumn(pos in b.go) of a.go = %d:%d, want false", line, col)
+	}
+	if _, _, ok := a.LineColumn(token.NoPos); ok {
+		t.Errorf("LineColumn(NoPos) = true, want false")
+	}
+}
+
+func TestSrcFileHash(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nvar A = 1\n",
+		"b.go": "package p\n\nvar B = 2\n",
+	})
+	srcFile := pkg.SrcFile("a.go")
+	var fileHash, pkgHash = srcFile.Hash(), pkg.Hash()
+	if len(fileHash) != 64 || srcFile.Hash() != fileHash {
+		t.Fatalf("Hash = %q, want a stable hex SHA-256", fileHash)
+	}
+	if copied := mustVirtualPackage(t, map[string]string{
+		"b.go": "package p\n\nvar B = 2\n",
+		"a.go": "package p\n\nvar A = 1\n",
+	}); copied.Hash() != pkgHash {
+		t.Errorf("Hash of copied package = %q, want %q", copied.Hash(), pkgHash)
+	}
+
+	if err := srcFile.update("package p\n\nvar A = 3\n", srcFile.Syntax(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Hash() == fileHash {
+		t.Errorf("Hash is not changed after update")
+	}
+	if pkg.Hash() == pkgHash {
+		t.Errorf("Hash of package is not changed after update")
+	}
+}
+
+func TestSrcFileIsTest(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"foo/foo.go":      "package foo\n\nfunc Foo() {}\n",
+		"foo/foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) { Foo() }\n",
+		"foo/bar_test.go": "package foo_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m/foo\"\n)\n\nfunc TestBar(t *testing.T) { foo.Foo() }\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkg, testPkg = mustPackage(t, prog, testModulePath+"/foo"), mustPackage(t, prog, testModulePath+"/foo_test")
+	for _, test := range []struct {
+		file     *SrcFile
+		isTest   bool
+		external bool
+	}{
+		{pkg.SrcFile(filepath.Join(rootDir, "foo", "foo.go")), false, false},
+		{pkg.SrcFile(filepath.Join(rootDir, "foo", "foo_test.go")), true, false},
+		{testPkg.SrcFile(filepath.Join(rootDir, "foo", "bar_test.go")), true, true},
+	} {
+		if test.file == nil {
+			t.Fatalf("file is not loaded")
+		}
+		if test.file.IsTest() != test.isTest || test.file.IsExternalTest() != test.external {
+			t.Errorf("%s: IsTest = %v and IsExternalTest = %v, want %v and %v", filepath.Base(test.file.Path()),
+				test.file.IsTest(), test.file.IsExternalTest(), test.isTest, test.external)
+		}
+	}
+}
+
+func TestSrcFileLines(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nfunc F() int {\n\treturn 1\n}\n",
+		"b.go": "package p\n\nvar B = 2",
+	})
+	var a, b = pkg.SrcFile("a.go"), pkg.SrcFile("b.go")
+	if got, want := a.Lines(), []string{"package p", "", "func F() int {", "\treturn 1", "}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+	if got := b.Lines(); len(got) != 3 || got[2] != "var B = 2" {
+		t.Errorf("Lines without final new line = %q", got)
+	}
+	if line, err := a.Line(4); err != nil || line != "\treturn 1" {
+		t.Errorf("Line(4) = %q, %v, want the return", line, err)
+	}
+	for _, n := range []int{0, 6} {
+		if line, err := a.Line(n); err == nil {
+			t.Errorf("Line(%d) = %q, want error", n, line)
+		}
+	}
+
+	var funcDecl = a.Functions()[0]
+	if got, want := a.LinesInRange(funcDecl.Pos(), funcDecl.End()), []string{"func F() int {", "\treturn 1", "}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LinesInRange(F) = %q, want %q", got, want)
+	}
+	if got := a.LinesInRange(funcDecl.End(), funcDecl.Pos()); got != nil {
+		t.Errorf("LinesInRange(reversed) = %q, want nil", got)
+	}
+	if got := a.LinesInRange(funcDecl.Pos(), b.Syntax().End()); got != nil {
+		t.Errorf("LinesInRange(end in b.go) = %q, want nil", got)
+	}
+}
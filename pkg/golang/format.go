@@ -0,0 +1,113 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the formatting of source code in SrcFile and Package as gofmt
+// does, which is used to verify the outputs of analysis passes rewriting the code, and to print the
+// syntax nodes in diagnostics.
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// FormattingReport records the errors in formatting the source files of a package, i.e. the files
+// that can't be parsed. It is returned as error by Package.Formatted if any file fails.
+type FormattingReport struct {
+	Errors map[string]error // Errors map from the paths of source files to errors in formatting
+}
+
+// Error lists the paths of source files failed to format along with their errors in sorted order.
+func (report *FormattingReport) Error() string {
+	var paths []string
+	for path := range report.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var messages []string
+	for _, path := range paths {
+		messages = append(messages, fmt.Sprintf("%s: %v", path, report.Errors[path]))
+	}
+	return fmt.Sprintf("can't format %d files: %s", len(paths), strings.Join(messages, "; "))
+}
+
+// Formatted maps the path of each source file in this package to its code formatted by gofmt (via
+// format.Source). If any file fails, the others are still returned along with a *FormattingReport
+// as error, which records the errors of the failed files.
+func (pkg *Package) Formatted() (map[string][]byte, error) {
+	if pkg == nil {
+		return nil, fmt.Errorf("nil package is used")
+	}
+	var formatted = make(map[string][]byte)
+	var report = &FormattingReport{Errors: make(map[string]error)}
+	for path, srcFile := range pkg.srcFiles {
+		if srcFile == nil {
+			continue
+		}
+		source, err := format.Source([]byte(srcFile.code))
+		if err != nil {
+			report.Errors[path] = err
+			continue
+		}
+		formatted[path] = source
+	}
+	if len(report.Errors) > 0 {
+		return formatted, report
+	}
+	return formatted, nil
+}
+
+// IsFormatted checks whether the code in this source file is formatted by gofmt, i.e. it's the same
+// as the output of format.Source. It returns error if the code can't be parsed.
+func (file *SrcFile) IsFormatted() (bool, error) {
+	if file == nil {
+		return false, fmt.Errorf("nil file is used")
+	}
+	source, err := format.Source([]byte(file.code))
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(source, []byte(file.code)), nil
+}
+
+// nodePrinter prints the syntax nodes in the same configuration as gofmt.
+var nodePrinter = &printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+// formatNode prints the syntax node in the normalized source by go/printer with the FileSet, or
+// returns empty if the node is nil.
+func formatNode(fileSet *token.FileSet, node ast.Node) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+	if fileSet == nil {
+		fileSet = token.NewFileSet()
+	}
+	var buffer bytes.Buffer
+	if err := nodePrinter.Fprint(&buffer, fileSet, node); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// FormatNode prints the syntax node (e.g. an expression, statement or declaration) in this source
+// file as normalized source, such that it can be displayed in diagnostics without tracking offsets.
+func (file *SrcFile) FormatNode(node ast.Node) (string, error) {
+	if file == nil || file.pkg == nil {
+		return formatNode(nil, node)
+	}
+	return formatNode(file.pkg.fileSet, node)
+}
+
+// FormatNode prints the syntax node in the source files of this package as normalized source.
+func (pkg *Package) FormatNode(node ast.Node) (string, error) {
+	if pkg == nil {
+		return formatNode(nil, node)
+	}
+	return formatNode(pkg.fileSet, node)
+}
@@ -0,0 +1,77 @@
+package golang
+
+import (
+	"errors"
+	"go/ast"
+	"testing"
+)
+
+func TestPackageFormatted(t *testing.T) {
+	const formatted = "package p\n\nfunc F() int { return 1 }\n"
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": formatted,
+		"b.go": "package p\nfunc  G( ) int {\nreturn 2}\n",
+	})
+	sources, err := pkg.Formatted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sources["a.go"]) != formatted {
+		t.Errorf("Formatted a.go = %q, want %q", sources["a.go"], formatted)
+	}
+	if want := "package p\n\nfunc G() int {\n\treturn 2\n}\n"; string(sources["b.go"]) != want {
+		t.Errorf("Formatted b.go = %q, want %q", sources["b.go"], want)
+	}
+	for name, want := range map[string]bool{"a.go": true, "b.go": false} {
+		if got, err := pkg.SrcFile(name).IsFormatted(); err != nil || got != want {
+			t.Errorf("IsFormatted(%s) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+
+	// the files failed to format are reported along with the others formatted
+	srcFile := pkg.SrcFile("b.go")
+	if err := srcFile.update("package p\nfunc {", srcFile.Syntax(), nil); err != nil {
+		t.Fatal(err)
+	}
+	sources, err = pkg.Formatted()
+	var report *FormattingReport
+	if !errors.As(err, &report) || len(report.Errors) != 1 || report.Errors["b.go"] == nil {
+		t.Fatalf("error = %v, want the report of b.go", err)
+	}
+	if _, ok := sources["b.go"]; ok || string(sources["a.go"]) != formatted {
+		t.Errorf("Formatted = %q, want only a.go", sources)
+	}
+	if _, err := srcFile.IsFormatted(); err == nil {
+		t.Errorf("IsFormatted(syntax error) = nil, want error")
+	}
+}
+
+func TestFormatNode(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\nfunc  G(x int ) int {\nreturn  x+ 1}\n",
+	})
+	var srcFile = pkg.SrcFile("a.go")
+	var funcDecl = srcFile.Functions()[0]
+	var result = funcDecl.Body.List[0].(*ast.ReturnStmt).Results[0]
+	for _, test := range []struct {
+		node ast.Node
+		want string
+	}{
+		{funcDecl, "func G(x int) int {\n\treturn x + 1\n}"},
+		{result, "x + 1"},
+		{nil, ""},
+	} {
+		if got, err := srcFile.FormatNode(test.node); err != nil || got != test.want {
+			t.Errorf("FormatNode = %q, %v, want %q", got, err, test.want)
+		}
+		if got, err := pkg.FormatNode(test.node); err != nil || got != test.want {
+			t.Errorf("FormatNode of package = %q, %v, want %q", got, err, test.want)
+		}
+	}
+	if got, err := (*SrcFile)(nil).FormatNode(result); err != nil || got != "x + 1" {
+		t.Errorf("FormatNode of nil file = %q, %v, want x + 1", got, err)
+	}
+	if got, err := srcFile.FormatNode(funcDecl.Type.Params.List[0]); err == nil {
+		t.Errorf("FormatNode(field) = %q, want error of unsupported node", got)
+	}
+}
@@ -0,0 +1,171 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the graphs derived from Program, which describe the relations
+// among its functions and packages for the interprocedural and inter-package analysis.
+package golang
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+)
+
+// CallGraph constructs the call graph of the program by class hierarchy analysis (CHA), in which
+// the dynamic calls are resolved to all methods implementing the interface. The SSA form of whole
+// program is built at first if not built yet, so the nodes refer to ssa.Function in SrcFile.Members.
+func (prog *Program) CallGraph() (graph *callgraph.Graph, err error) {
+	if prog == nil {
+		return nil, fmt.Errorf("nil program is used")
+	}
+	if prog.ssaProg == nil {
+		if _, buildErr := prog.BuildSSA(); buildErr != nil {
+			return nil, buildErr
+		}
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			graph, err = nil, fmt.Errorf("can't construct call graph: %v", e)
+		}
+	}()
+	graph = cha.CallGraph(prog.ssaProg)
+	graph.DeleteSyntheticNodes()
+	return graph, nil
+}
+
+// DependencyGraph maps the path of each package in the program to the sorted paths of the packages
+// it directly imports, which are resolved only to those in the program (excluding the standard and
+// external ones). The packages importing nothing in the program are mapped to empty lists.
+func (prog *Program) DependencyGraph() map[string][]string {
+	if prog == nil {
+		return nil
+	}
+	var graph = make(map[string][]string)
+	for _, pkg := range prog.AllPackages() {
+		var deps = make([]string, 0, len(pkg.imports))
+		for _, importPath := range pkg.imports {
+			if importPath != pkg.pkgPath && prog.Package(importPath) != nil {
+				deps = append(deps, importPath)
+			}
+		}
+		sort.Strings(deps)
+		graph[pkg.pkgPath] = deps
+	}
+	return graph
+}
+
+// ReverseDependencyGraph is the transpose of DependencyGraph, which maps the path of each package in
+// the program to the sorted paths of the packages in program directly importing it.
+func (prog *Program) ReverseDependencyGraph() map[string][]string {
+	var graph = prog.DependencyGraph()
+	if graph == nil {
+		return nil
+	}
+	var reverse = make(map[string][]string, len(graph))
+	for pkgPath := range graph {
+		reverse[pkgPath] = []string{}
+	}
+	for pkgPath, deps := range graph {
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], pkgPath)
+		}
+	}
+	for _, users := range reverse {
+		sort.Strings(users)
+	}
+	return reverse
+}
+
+// WriteDOT writes the import graph of packages in the program as a GraphViz DOT digraph, in which
+// each node is a package labelled by its path, and each edge is an import between the packages in
+// the program (excluding the standard and external ones), both in sorted order.
+func (prog *Program) WriteDOT(w io.Writer) error {
+	if prog == nil {
+		return fmt.Errorf("nil program is used")
+	}
+	var graph = prog.DependencyGraph()
+	var pkgPaths = make([]string, 0, len(graph))
+	for pkgPath := range graph {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+
+	var lines = []string{"digraph packages {"}
+	for _, pkgPath := range pkgPaths {
+		lines = append(lines, fmt.Sprintf("\t%s [label=%s];", strconv.Quote(pkgPath), strconv.Quote(pkgPath)))
+	}
+	for _, pkgPath := range pkgPaths {
+		for _, dep := range graph[pkgPath] {
+			lines = append(lines, fmt.Sprintf("\t%s -> %s;", strconv.Quote(pkgPath), strconv.Quote(dep)))
+		}
+	}
+	lines = append(lines, "}")
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packagesOfGraph returns the packages in the program of which paths are mapped to no other package
+// in the graph (DependencyGraph or its reverse), sorted by their paths.
+func (prog *Program) packagesOfGraph(graph map[string][]string) []*Package {
+	var pkgs []*Package
+	for pkgPath, adjacent := range graph {
+		if pkg := prog.Package(pkgPath); pkg != nil && len(adjacent) == 0 {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].pkgPath < pkgs[j].pkgPath })
+	return pkgs
+}
+
+// LeafPackages returns the packages importing no other package in the program (typically utility
+// or helper packages), sorted by their paths.
+func (prog *Program) LeafPackages() []*Package {
+	return prog.packagesOfGraph(prog.DependencyGraph())
+}
+
+// RootPackages returns the packages not imported by any other package in the program (typically the
+// entry points), sorted by their paths.
+func (prog *Program) RootPackages() []*Package {
+	return prog.packagesOfGraph(prog.ReverseDependencyGraph())
+}
+
+// DependencyHeatMap maps the path of each package in the program to its in-degree in the import
+// graph, i.e. the number of packages in the program directly importing it (0 for the roots).
+func (prog *Program) DependencyHeatMap() map[string]int {
+	var reverse = prog.ReverseDependencyGraph()
+	if reverse == nil {
+		return nil
+	}
+	var heatMap = make(map[string]int, len(reverse))
+	for pkgPath, users := range reverse {
+		heatMap[pkgPath] = len(users)
+	}
+	return heatMap
+}
+
+// SharedPackages returns the packages directly imported by at least threshold packages in program,
+// which are sorted by the numbers of their importers in descending order (then by their paths).
+func (prog *Program) SharedPackages(threshold int) []*Package {
+	var heatMap = prog.DependencyHeatMap()
+	var pkgs []*Package
+	for pkgPath, count := range heatMap {
+		if pkg := prog.Package(pkgPath); pkg != nil && count >= threshold {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		if heatMap[pkgs[i].pkgPath] != heatMap[pkgs[j].pkgPath] {
+			return heatMap[pkgs[i].pkgPath] > heatMap[pkgs[j].pkgPath]
+		}
+		return pkgs[i].pkgPath < pkgs[j].pkgPath
+	})
+	return pkgs
+}
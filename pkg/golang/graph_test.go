@@ -0,0 +1,159 @@
+package golang
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph"
+)
+
+func TestProgramCallGraph(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\nfunc A() int { return b.B() }\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/c\"\n\nfunc B() int { return c.C() }\n",
+		"c/c.go": "package c\n\nfunc C() int { return 1 }\n\nfunc D() int { return 2 }\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := prog.CallGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a = mustPackage(t, prog, testModulePath+"/a").SSAPkg().Func("A")
+	var c = mustPackage(t, prog, testModulePath+"/c").SSAPkg().Func("C")
+	var d = mustPackage(t, prog, testModulePath+"/c").SSAPkg().Func("D")
+	if graph.Nodes[a] == nil || graph.Nodes[c] == nil {
+		t.Fatalf("A and C are not nodes of call graph")
+	}
+
+	// the functions reachable from A by the edges of call graph
+	var reachable = map[*callgraph.Node]bool{graph.Nodes[a]: true}
+	var worklist = []*callgraph.Node{graph.Nodes[a]}
+	for len(worklist) > 0 {
+		node := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		for _, edge := range node.Out {
+			if !reachable[edge.Callee] {
+				reachable[edge.Callee] = true
+				worklist = append(worklist, edge.Callee)
+			}
+		}
+	}
+	if !reachable[graph.Nodes[c]] {
+		t.Errorf("C is not reachable from A")
+	}
+	if node := graph.Nodes[d]; node != nil && reachable[node] {
+		t.Errorf("D is reachable from A")
+	}
+}
+
+// graphFixture loads the packages a, b and c, where a imports b, c and fmt, and b imports c.
+func graphFixture(t *testing.T) *Program {
+	t.Helper()
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/m/b\"\n\t\"example.com/m/c\"\n)\n\nvar _ = fmt.Sprint(b.B, c.C)\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/c\"\n\nvar B = c.C\n",
+		"c/c.go": "package c\n\nconst C = 1\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog
+}
+
+func TestProgramDependencyGraph(t *testing.T) {
+	prog := graphFixture(t)
+	var a, b, c = testModulePath + "/a", testModulePath + "/b", testModulePath + "/c"
+	var graph = map[string][]string{a: {b, c}, b: {c}, c: {}}
+	if got := prog.DependencyGraph(); !reflect.DeepEqual(got, graph) {
+		t.Errorf("DependencyGraph = %v, want %v", got, graph)
+	}
+	var reverse = map[string][]string{a: {}, b: {a}, c: {a, b}}
+	if got := prog.ReverseDependencyGraph(); !reflect.DeepEqual(got, reverse) {
+		t.Errorf("ReverseDependencyGraph = %v, want %v", got, reverse)
+	}
+	var nilProg *Program
+	if nilProg.DependencyGraph() != nil || nilProg.ReverseDependencyGraph() != nil {
+		t.Errorf("nil program has dependencies")
+	}
+}
+
+func TestProgramWriteDOT(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := graphFixture(t).WriteDOT(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if lines[0] != "digraph packages {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("WriteDOT = %q, want a digraph", buffer.String())
+	}
+	var nodes, edges []string
+	for _, line := range lines[1 : len(lines)-1] {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ";")
+		if from, to, ok := strings.Cut(line, " -> "); ok {
+			edges = append(edges, strings.Trim(from, `"`)+"->"+strings.Trim(to, `"`))
+		} else if name, label, ok := strings.Cut(line, " [label="); ok && strings.Trim(label, `"]`) == strings.Trim(name, `"`) {
+			nodes = append(nodes, strings.Trim(name, `"`))
+		} else {
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	var a, b, c = testModulePath + "/a", testModulePath + "/b", testModulePath + "/c"
+	if want := []string{a, b, c}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes = %v, want %v", nodes, want)
+	}
+	if want := []string{a + "->" + b, a + "->" + c, b + "->" + c}; !reflect.DeepEqual(edges, want) {
+		t.Errorf("edges = %v, want %v", edges, want)
+	}
+}
+
+func TestProgramLeafAndRootPackages(t *testing.T) {
+	prog := graphFixture(t)
+	var pathsOf = func(pkgs []*Package) []string {
+		var pkgPaths []string
+		for _, pkg := range pkgs {
+			pkgPaths = append(pkgPaths, pkg.PkgPath())
+		}
+		return pkgPaths
+	}
+	if got, want := pathsOf(prog.LeafPackages()), []string{testModulePath + "/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LeafPackages = %v, want %v", got, want)
+	}
+	if got, want := pathsOf(prog.RootPackages()), []string{testModulePath + "/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RootPackages = %v, want %v", got, want)
+	}
+	var nilProg *Program
+	if nilProg.LeafPackages() != nil || nilProg.RootPackages() != nil {
+		t.Errorf("nil program has packages")
+	}
+}
+
+func TestProgramSharedPackages(t *testing.T) {
+	prog := graphFixture(t)
+	var want = map[string]int{testModulePath + "/a": 0, testModulePath + "/b": 1, testModulePath + "/c": 2}
+	if got := prog.DependencyHeatMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DependencyHeatMap = %v, want %v", got, want)
+	}
+	for _, test := range []struct {
+		threshold int
+		pkgPaths  []string
+	}{
+		{0, []string{testModulePath + "/c", testModulePath + "/b", testModulePath + "/a"}},
+		{1, []string{testModulePath + "/c", testModulePath + "/b"}},
+		{2, []string{testModulePath + "/c"}},
+		{3, nil},
+	} {
+		var pkgPaths []string
+		for _, pkg := range prog.SharedPackages(test.threshold) {
+			pkgPaths = append(pkgPaths, pkg.PkgPath())
+		}
+		if !reflect.DeepEqual(pkgPaths, test.pkgPaths) {
+			t.Errorf("SharedPackages(%d) = %v, want %v", test.threshold, pkgPaths, test.pkgPaths)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the importer used to resolve the imported packages in type
+// checking the packages of Program, which prefers the source of vendored dependencies in module.
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VendorDirName is the name of directory under module root where the dependencies are vendored
+const VendorDirName = "vendor"
+
+// progImporter resolves the imported packages in type checking the packages of a Program. Those in
+// the module are loaded into the program on demand, and those vendored under the module's `vendor`
+// directory are type-checked from source, while the others are imported by the default importer.
+//
+// Resolving the module's packages to those in the program ensures that references across packages
+// share the same types.Object, which is required by interprocedural analysis over the program.
+type progImporter struct {
+	program   *Program                  // program is the Program of which packages are imported
+	options   *LoadOptions              // options configure the loading of vendored packages
+	vendorDir string                    // vendorDir is the absolute path of vendor, or empty if none
+	fileSet   *token.FileSet            // fileSet positions the syntax of the vendored packages
+	fallback  types.Importer            // fallback imports the packages that aren't vendored
+	packages  map[string]*types.Package // packages map from import paths to the loaded packages
+	importing map[string]bool           // importing are import paths being type-checked now
+}
+
+// newProgImporter creates the importer to resolve imports in packages of the program.
+func newProgImporter(program *Program, opts *LoadOptions) *progImporter {
+	var vendorDir string
+	if program != nil && program.module != nil {
+		dirPath := filepath.Join(program.module.RootPath, VendorDirName)
+		if dirInfo, err := os.Stat(dirPath); err == nil && dirInfo.IsDir() {
+			vendorDir = dirPath
+		}
+	}
+	var fileSet = program.FileSet()
+	if fileSet == nil {
+		fileSet = token.NewFileSet()
+	}
+	var fallback = importer.Default()
+	if opts != nil && opts.Importer != nil {
+		fallback = opts.Importer
+	}
+	return &progImporter{
+		program:   program,
+		options:   opts,
+		vendorDir: vendorDir,
+		fileSet:   fileSet,
+		fallback:  fallback,
+		packages:  make(map[string]*types.Package),
+		importing: make(map[string]bool),
+	}
+}
+
+// Import returns the package imported by the path, resolving module and vendored packages first.
+func (imp *progImporter) Import(importPath string) (*types.Package, error) {
+	if typePkg, ok := imp.packages[importPath]; ok {
+		return typePkg, nil
+	}
+	if pkg := imp.program.Package(importPath); pkg != nil && pkg.typePkg != nil {
+		return pkg.typePkg, nil
+	}
+	if dirPath, ok := imp.program.Module().dirOf(importPath); ok {
+		return imp.importModule(importPath, dirPath)
+	}
+	if len(imp.vendorDir) > 0 {
+		vendorPath := filepath.Join(imp.vendorDir, filepath.FromSlash(importPath))
+		if dirInfo, err := os.Stat(vendorPath); err == nil && dirInfo.IsDir() {
+			return imp.importVendored(importPath, vendorPath)
+		}
+	}
+	return imp.fallback.Import(importPath)
+}
+
+// importModule loads the package of module in the directory into program and returns its types.
+func (imp *progImporter) importModule(importPath, dirPath string) (*types.Package, error) {
+	// 1. avoid the cyclic imports of module packages
+	if imp.importing[importPath] {
+		return nil, fmt.Errorf("import cycle: %s", importPath)
+	}
+	imp.importing[importPath] = true
+	defer delete(imp.importing, importPath)
+
+	// 2. load the package from directory into program
+	pkgs, loadErr := imp.program.loadDirectory(dirPath, imp.options, false)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	for _, pkg := range pkgs {
+		if pkg.pkgPath == importPath && pkg.typePkg != nil {
+			return pkg.typePkg, nil
+		}
+	}
+	for _, pkg := range pkgs {
+		if !strings.HasSuffix(pkg.pkgName, "_test") && pkg.typePkg != nil {
+			return pkg.typePkg, nil // the package name differs from its directory
+		}
+	}
+	return nil, fmt.Errorf("can't import: %s", importPath)
+}
+
+// importVendored type-checks the non-test source files of vendored package in the directory.
+func (imp *progImporter) importVendored(importPath, dirPath string) (*types.Package, error) {
+	// 1. avoid the cyclic imports of vendored packages
+	if imp.importing[importPath] {
+		return nil, fmt.Errorf("import cycle: %s", importPath)
+	}
+	imp.importing[importPath] = true
+	defer delete(imp.importing, importPath)
+
+	// 2. parse the non-test source files in directory
+	dir, parseErr := parseGoDirectory(imp.fileSet, dirPath, imp.options)
+	if parseErr != nil && (dir == nil || len(dir.astPkgs) == 0) {
+		return nil, parseErr
+	}
+	astPkgs := dir.astPkgs
+	var pkgNames []string
+	for pkgName := range astPkgs {
+		if !strings.HasSuffix(pkgName, "_test") {
+			pkgNames = append(pkgNames, pkgName)
+		}
+	}
+	if len(pkgNames) == 0 {
+		return nil, fmt.Errorf("%w in: %s", ErrNoPackages, dirPath)
+	}
+	sort.Strings(pkgNames)
+	var srcPaths []string
+	for srcPath := range astPkgs[pkgNames[0]].Files {
+		if !strings.HasSuffix(srcPath, "_test"+GoFileSuffix) {
+			srcPaths = append(srcPaths, srcPath)
+		}
+	}
+	sort.Strings(srcPaths)
+	var astFiles = make([]*ast.File, 0, len(srcPaths))
+	for _, srcPath := range srcPaths {
+		astFiles = append(astFiles, astPkgs[pkgNames[0]].Files[srcPath])
+	}
+
+	// 3. perform the type checking with this importer
+	typeConf := &types.Config{
+		Error:    func(err error) { /* do nothing */ },
+		Importer: imp,
+		Sizes:    types.SizesFor("gc", imp.options.goarch()),
+	}
+	typePkg, _ := checkTypes(typeConf, importPath, imp.fileSet, astFiles, nil)
+	if typePkg == nil {
+		return nil, fmt.Errorf("can't create types.Package: %s", importPath)
+	}
+	imp.packages[importPath] = typePkg
+	return typePkg, nil
+}
+
+// importerOf returns the importer used to type-check the packages in the program, or the importer
+// in options (or GOROOT types by default) if program or its module is not given.
+func (prog *Program) importerOf(opts *LoadOptions) types.Importer {
+	if prog == nil || prog.module == nil {
+		if opts != nil && opts.Importer != nil {
+			return opts.Importer
+		}
+		return importer.Default()
+	}
+	if prog.importer == nil {
+		prog.importer = newProgImporter(prog, opts)
+	}
+	return prog.importer
+}
@@ -0,0 +1,101 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the analysis on memory layout of struct types declared in the
+// SrcFile, which computes the bytes wasted by alignment padding between (and after) the fields.
+package golang
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// structLayout computes the padding bytes of the struct in its declared order of fields, and those
+// after reordering the fields by their alignments in descending order (which minimizes padding).
+// It returns false if the sizes can't be computed, e.g. panics on the invalid types.
+func structLayout(st *types.Struct, sizes types.Sizes) (padding, optimal int64, ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			padding, optimal, ok = 0, 0, false
+		}
+	}()
+	// 1. compute the padding in the declared order
+	var fields = make([]*types.Var, 0, st.NumFields())
+	var fieldsSize int64
+	for i := 0; i < st.NumFields(); i++ {
+		fields = append(fields, st.Field(i))
+		fieldsSize += sizes.Sizeof(st.Field(i).Type())
+	}
+	padding = sizes.Sizeof(st) - fieldsSize
+
+	// 2. compute the padding after reordering fields
+	sort.SliceStable(fields, func(i, j int) bool {
+		return sizes.Alignof(fields[i].Type()) > sizes.Alignof(fields[j].Type())
+	})
+	optimal = sizes.Sizeof(types.NewStruct(fields, nil)) - fieldsSize
+	if optimal > padding {
+		optimal = padding // never worse than the declared order
+	}
+	return padding, optimal, true
+}
+
+// structTypesOf returns the struct types declared by the type specs in the source file, mapped by
+// the type names. It returns nil if the file's syntax or type info isn't loaded.
+func (file *SrcFile) structTypesOf() map[string]*types.Struct {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var structs = make(map[string]*types.Struct)
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		typeSpec, ok := node.(*ast.TypeSpec)
+		if !ok || typeSpec.TypeParams != nil || typeSpec.Assign.IsValid() {
+			return true // the layout of generic type depends on its instantiation
+		}
+		typeObj, ok := file.pkg.typInfo.Defs[typeSpec.Name].(*types.TypeName)
+		if !ok || typeObj == nil {
+			return true
+		}
+		if st, ok := typeObj.Type().Underlying().(*types.Struct); ok {
+			structs[typeSpec.Name.Name] = st
+		}
+		return true
+	})
+	return structs
+}
+
+// AlignmentPadding maps the name of each struct type declared in the source file to the number of
+// bytes wasted by alignment padding in its declared order of fields. Use AlignmentSavings to know
+// how many of them can be saved by reordering the fields.
+func (file *SrcFile) AlignmentPadding() map[string]int64 {
+	var structs = file.structTypesOf()
+	if structs == nil {
+		return nil
+	}
+	var paddings = make(map[string]int64)
+	var sizes = file.pkg.sizesOf()
+	for name, st := range structs {
+		if padding, _, ok := structLayout(st, sizes); ok {
+			paddings[name] = padding
+		}
+	}
+	return paddings
+}
+
+// AlignmentSavings maps the name of each struct type declared in the source file, whose size could
+// be reduced by reordering its fields (by alignments in descending order), to the estimated bytes
+// saved. The structs that are already optimally ordered are excluded.
+func (file *SrcFile) AlignmentSavings() map[string]int64 {
+	var structs = file.structTypesOf()
+	if structs == nil {
+		return nil
+	}
+	var savings = make(map[string]int64)
+	var sizes = file.pkg.sizesOf()
+	for name, st := range structs {
+		if padding, optimal, ok := structLayout(st, sizes); ok && padding > optimal {
+			savings[name] = padding - optimal
+		}
+	}
+	return savings
+}
@@ -0,0 +1,48 @@
+package golang
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAlignmentPadding(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{"p/p.go": `package p
+
+type Bad struct {
+	a bool
+	b int64
+	c bool
+}
+
+type Good struct {
+	b    int64
+	a, c bool
+}
+
+type Packed struct{ a, b int32 }
+
+type Generic[T any] struct {
+	a bool
+	v T
+}
+`})
+	prog, err := Load(rootDir, WithGOARCH("amd64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcFile := mustPackage(t, prog, testModulePath+"/p").SrcFile(filepath.Join(rootDir, "p", "p.go"))
+	var paddings = map[string]int64{"Bad": 14, "Good": 6, "Packed": 0}
+	if got := srcFile.AlignmentPadding(); !reflect.DeepEqual(got, paddings) {
+		t.Errorf("AlignmentPadding = %v, want %v", got, paddings)
+	}
+	var savings = map[string]int64{"Bad": 8}
+	if got := srcFile.AlignmentSavings(); !reflect.DeepEqual(got, savings) {
+		t.Errorf("AlignmentSavings = %v, want %v", got, savings)
+	}
+
+	var unchecked *SrcFile
+	if unchecked.AlignmentPadding() != nil || unchecked.AlignmentSavings() != nil {
+		t.Errorf("nil SrcFile has paddings")
+	}
+}
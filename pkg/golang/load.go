@@ -1,17 +1,19 @@
 package golang
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
-	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -91,21 +93,34 @@ func inferGoPkgInfo(module *Module, file string) (string, string, string, error)
 	return pkgPath, filepath.Base(pkgDir), pkgDir, nil
 }
 
-// newDefaultTypeConfig returns types.Config in default template.
-func newDefaultTypeConfig() *types.Config {
+// newDefaultTypeConfig returns types.Config in default template for the target platform in options,
+// which resolves the imports by the importer of program (or GOROOT types if program is nil).
+func newDefaultTypeConfig(program *Program, opts *LoadOptions) *types.Config {
+	sizes := types.SizesFor("gc", opts.goarch())
+	if sizes == nil {
+		sizes = types.SizesFor("gc", build.Default.GOARCH)
+	}
 	return &types.Config{
 		Context:                  types.NewContext(),
 		IgnoreFuncBodies:         false,
 		FakeImportC:              false,
 		Error:                    func(err error) { /* do nothing */ },
-		Importer:                 importer.Default(), // GOROOT types
-		Sizes:                    types.SizesFor("gc", build.Default.GOARCH),
+		Importer:                 program.importerOf(opts),
+		Sizes:                    sizes,
 		DisableUnusedImportCheck: false,
 	}
 }
 
-// newDefaultTypeInfo returns types.Info in the default template.
-func newDefaultTypeInfo() *types.Info {
+// newDefaultTypeInfo returns types.Info in the default template, or the minimal one with only
+// Types, Defs and Uses if it is required in options.
+func newDefaultTypeInfo(opts *LoadOptions) *types.Info {
+	if opts != nil && opts.MinimalTypeInfo {
+		return &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+	}
 	return &types.Info{
 		Types:      make(map[ast.Expr]types.TypeAndValue),
 		Instances:  make(map[*ast.Ident]types.Instance),
@@ -118,25 +133,124 @@ func newDefaultTypeInfo() *types.Info {
 	}
 }
 
+// readSourceCode reads the source code of file from the overlay in options, or from the disk if it
+// is not overlaid.
+func readSourceCode(srcPath string, opts *LoadOptions) ([]byte, error) {
+	if content, ok := opts.overlayOf(srcPath); ok {
+		return content, nil
+	}
+	return os.ReadFile(srcPath)
+}
+
+// parsedDir records the syntax trees of packages parsed from the source files in a directory.
+type parsedDir struct {
+	astPkgs map[string]*ast.Package  // astPkgs map from the package names to their syntax
+	ignored []string                 // ignored are source files excluded by build constraints
+	ignPkgs map[string]string        // ignPkgs map from the ignored files to their package names
+	elapsed map[string]time.Duration // elapsed map from source files to time of reading and parsing
+	sources map[string][]byte        // sources map from source files to the bytes being parsed
+	builtIn []*Package               // builtIn are all packages built from the directory, or nil
+}
+
+// parseGoDirectory parses the source files in the directory (including those only in the overlay)
+// whose build constraints match the target platform in options (excluding the test files if tests
+// are excluded), and groups their syntax trees by the package names, along with paths of the files
+// excluded by constraints.
+//
+// Like parser.ParseDir, it returns the packages being parsed along with the first error if any.
+func parseGoDirectory(fileSet *token.FileSet, dirPath string, opts *LoadOptions) (*parsedDir, error) {
+	// 1. collect the source files in the directory and overlay
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	var srcPaths []string
+	var srcPathSet = make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), GoFileSuffix) &&
+			!opts.excludesFile(entry.Name()) {
+			srcPath := filepath.Join(dirPath, entry.Name())
+			srcPaths = append(srcPaths, srcPath)
+			srcPathSet[srcPath] = true
+		}
+	}
+	if opts != nil {
+		for overlayPath := range opts.Overlay {
+			srcPath := filepath.Clean(overlayPath)
+			if filepath.Dir(srcPath) == dirPath && !srcPathSet[srcPath] &&
+				strings.HasSuffix(srcPath, GoFileSuffix) && !opts.excludesFile(filepath.Base(srcPath)) {
+				srcPaths = append(srcPaths, srcPath)
+				srcPathSet[srcPath] = true
+			}
+		}
+	}
+	sort.Strings(srcPaths)
+
+	// 2. parse the source files matching build constraints
+	var firstErr error
+	var dir = &parsedDir{
+		astPkgs: make(map[string]*ast.Package),
+		ignored: nil,
+		ignPkgs: make(map[string]string),
+		elapsed: make(map[string]time.Duration),
+		sources: make(map[string][]byte),
+	}
+	var buildContext = newBuildContext(opts)
+	for _, srcPath := range srcPaths {
+		begTime := time.Now()
+		srcBytes, readErr := readSourceCode(srcPath, opts)
+		if readErr != nil {
+			if firstErr == nil {
+				firstErr = readErr
+			}
+			continue
+		}
+		if !matchGoFile(buildContext, srcPath, srcBytes, &dir.ignored) {
+			// the package clause is parsed from bytes in hand, which might be an overlay
+			header, _ := parser.ParseFile(token.NewFileSet(), srcPath, srcBytes, parser.PackageClauseOnly)
+			if header != nil && header.Name != nil {
+				dir.ignPkgs[srcPath] = header.Name.Name
+			}
+			continue
+		}
+		syntax, parseErr := parser.ParseFile(fileSet, srcPath, srcBytes, parser.ParseComments)
+		dir.elapsed[srcPath] = time.Since(begTime)
+		dir.sources[srcPath] = srcBytes
+		if parseErr != nil {
+			if firstErr == nil {
+				firstErr = parseErr
+			}
+			continue
+		}
+		astPkg, ok := dir.astPkgs[syntax.Name.Name]
+		if !ok {
+			astPkg = &ast.Package{Name: syntax.Name.Name, Files: make(map[string]*ast.File)}
+			dir.astPkgs[syntax.Name.Name] = astPkg
+		}
+		astPkg.Files[srcPath] = syntax
+	}
+	return dir, firstErr
+}
+
 // parseSourceFileByFree freely builds the source file using syntax parser and
 // a basic type checking mode.
-func parseSourceFileByFree(srcFile *SrcFile) error {
+func parseSourceFileByFree(srcFile *SrcFile, opts *LoadOptions) error {
 	// 1. read the source code
 	if srcFile == nil || srcFile.Package() == nil {
 		return fmt.Errorf("incomplete: %s", srcFile.Path())
 	}
-	var srcBytes, readErr = os.ReadFile(srcFile.Path())
+	var srcBytes, readErr = readSourceCode(srcFile.Path(), opts)
 	if readErr != nil {
 		return readErr
 	}
 	if len(srcBytes) == 0 {
-		return fmt.Errorf("empty file: %s", srcFile.Path())
+		return fmt.Errorf("%w: %s", ErrEmptyFile, srcFile.Path())
 	}
 
 	// 2. parse the syntax
 	var fileSet = token.NewFileSet()
 	var syntax, parseErr = parser.ParseFile(
-		fileSet, srcFile.Path(), nil, parser.ParseComments)
+		fileSet, srcFile.Path(), srcBytes, parser.ParseComments)
 	if parseErr != nil {
 		return parseErr
 	}
@@ -146,9 +260,9 @@ func parseSourceFileByFree(srcFile *SrcFile) error {
 	_ = srcFile.update(string(srcBytes), syntax, nil)
 
 	// 3. perform default type checking
-	typeConf := newDefaultTypeConfig()
-	typeInfo := newDefaultTypeInfo()
-	typePkg, typeErr := typeConf.Check(srcFile.Package().PkgPath(), fileSet, []*ast.File{syntax}, typeInfo)
+	typeConf := newDefaultTypeConfig(srcFile.Package().Program(), opts)
+	typeInfo := newDefaultTypeInfo(opts)
+	typePkg, typeErr := checkTypes(typeConf, srcFile.Package().PkgPath(), fileSet, []*ast.File{syntax}, typeInfo)
 	if typePkg == nil {
 		return fmt.Errorf("can't create types.Package: %s", srcFile.Package().PkgPath())
 	}
@@ -159,6 +273,7 @@ func parseSourceFileByFree(srcFile *SrcFile) error {
 	pkg.typePkg = typePkg
 	pkg.typInfo = typeInfo
 	pkg.typSize = &typeConf.Sizes
+	pkg.importer = typeConf.Importer
 	for _, importSpec := range syntax.Imports {
 		if importSpec != nil && importSpec.Path != nil {
 			importPath := strings.Trim(importSpec.Path.Value, "\"")
@@ -182,26 +297,45 @@ func parseSourceFileByFree(srcFile *SrcFile) error {
 	return nil
 }
 
+// checkTypes type-checks the syntax of files as the package of path by the config, which recovers the
+// panic of checker on pathological inputs as a type error, along with an empty package (named by the
+// first file) such that loading a whole program is not crashed by one bad package.
+func checkTypes(typeConf *types.Config, pkgPath string, fileSet *token.FileSet, files []*ast.File,
+	typeInfo *types.Info) (typePkg *types.Package, typeErr error) {
+	defer func() {
+		if e := recover(); e != nil {
+			var pkgName string
+			if len(files) > 0 && files[0] != nil && files[0].Name != nil {
+				pkgName = files[0].Name.Name
+			}
+			typePkg = types.NewPackage(pkgPath, pkgName)
+			typeErr = fmt.Errorf("type checking panics in %s: %v", pkgPath, e)
+		}
+	}()
+	return typeConf.Check(pkgPath, fileSet, files, typeInfo)
+}
+
 // loadSourceFileByFree 'freely' loads the source file in the given path, then
 // return the SrcFile object (along with its Package and Program), if possible.
 //
 // If no 'go.mod' is found in the parent directories of source file, then this
 // function returns a SrcFile, with only the Package from the parent directory.
-func loadSourceFileByFree(codeFile string) (*SrcFile, error) {
+func loadSourceFileByFree(codeFile string, opts *LoadOptions) (*SrcFile, error) {
 	// 1. validate the input go source file
 	codePath, _ := filepath.Abs(codeFile)
 	fileInfo, err := os.Stat(codePath)
 	if os.IsNotExist(err) {
 		return nil, err
 	} else if fileInfo.IsDir() {
-		return nil, fmt.Errorf("not go file: %s", codePath)
+		return nil, fmt.Errorf("%w: %s", ErrNotGoFile, codePath)
 	} else if !strings.HasSuffix(codePath, GoFileSuffix) {
-		return nil, fmt.Errorf(" not go file: %s", codePath)
+		return nil, fmt.Errorf("%w: %s", ErrNotGoFile, codePath)
 	}
 
 	// 2. infer package path, name and dir
 	program, _ := initProgram(filepath.Dir(codePath))
 	if program != nil && program.module != nil {
+		program.options = opts
 		pkgPath, pkgName, pkgDir, err := inferGoPkgInfo(program.module, codePath)
 		if err != nil {
 			return nil, fmt.Errorf("can't get package: %v", err.Error())
@@ -214,7 +348,7 @@ func loadSourceFileByFree(codeFile string) (*SrcFile, error) {
 		if srcFile == nil {
 			return nil, fmt.Errorf("can't new source file: %s", codePath)
 		}
-		parseErr := parseSourceFileByFree(srcFile)
+		parseErr := parseSourceFileByFree(srcFile, opts)
 		if parseErr != nil {
 			return nil, parseErr
 		}
@@ -235,22 +369,27 @@ func loadSourceFileByFree(codeFile string) (*SrcFile, error) {
 	if srcFile == nil {
 		return nil, fmt.Errorf("can't new source file: %s", codePath)
 	}
-	parseErr := parseSourceFileByFree(srcFile)
+	parseErr := parseSourceFileByFree(srcFile, opts)
 	if parseErr != nil {
 		return nil, parseErr
 	}
 	return srcFile, nil
 }
 
-// parseGoPackageByFree freely parses the package with the info of syntax pkg.
-// It returns the load error if parsing failed.
-func parseGoPackageByFree(pkg *Package, astPkg *ast.Package) error {
+// parseGoPackageByFree freely parses the package with the info of syntax pkg
+// parsed in the directory. It returns the load error if parsing failed. The code
+// of source files is the bytes that were parsed, rather than re-read from disk.
+func parseGoPackageByFree(pkg *Package, dir *parsedDir, opts *LoadOptions) error {
 	// 1. initialize the loading info
-	if pkg == nil || astPkg == nil || len(astPkg.Files) == 0 {
-		return fmt.Errorf("no go files in: %v", pkg)
+	if pkg == nil || dir == nil {
+		return fmt.Errorf("%w in: %v", ErrNoPackages, pkg)
+	}
+	astPkg := dir.astPkgs[pkg.pkgName]
+	if astPkg == nil || len(astPkg.Files) == 0 {
+		return fmt.Errorf("%w in: %v", ErrNoPackages, pkg)
 	}
-	loadInfo := &LoadInfo{LoadTime: time.Now()}
-	pkg.loadInfo = loadInfo
+	loadInfo := &LoadInfo{LoadTime: time.Now(), FileLoadTimes: make(map[string]time.Duration)}
+	pkg.loadInfo, pkg.options = loadInfo, opts
 
 	// 2. construct each source file in package
 	var astFiles []*ast.File
@@ -259,26 +398,31 @@ func parseGoPackageByFree(pkg *Package, astPkg *ast.Package) error {
 			continue
 		}
 		var srcPath = pkg.fileSet.Position(syntax.Pos()).Filename
+		var bytes, parsed = dir.sources[srcPath]
 		srcPath, _ = filepath.Abs(srcPath)
-		var bytes, readErr = os.ReadFile(srcPath)
+		var readErr error
+		if !parsed {
+			bytes, readErr = readSourceCode(srcPath, opts)
+		}
 		if readErr != nil {
 			loadInfo.FileErrors = append(loadInfo.FileErrors, readErr)
 			continue
 		} else if len(bytes) == 0 {
 			loadInfo.FileErrors = append(loadInfo.FileErrors,
-				fmt.Errorf("empty file: %s", srcPath))
+				fmt.Errorf("%w: %s", ErrEmptyFile, srcPath))
 			continue
 		}
 		var srcFile = pkg.newSrcFile(srcPath)
 		_ = srcFile.update(string(bytes), syntax, nil)
 		astFiles = append(astFiles, syntax)
 		loadInfo.LoadedFiles = append(loadInfo.LoadedFiles, srcPath)
+		loadInfo.FileLoadTimes[srcPath] = dir.elapsed[srcPath]
 	}
 
 	// 3. perform the type checking
-	typeConf := newDefaultTypeConfig()
-	typeInfo := newDefaultTypeInfo()
-	typePkg, typeErr := typeConf.Check(pkg.PkgPath(), pkg.FileSet(), astFiles, typeInfo)
+	typeConf := newDefaultTypeConfig(pkg.Program(), opts)
+	typeInfo := newDefaultTypeInfo(opts)
+	typePkg, typeErr := checkTypes(typeConf, pkg.PkgPath(), pkg.FileSet(), astFiles, typeInfo)
 	if typeErr != nil {
 		loadInfo.IllTyped = true
 		loadInfo.TypeErrors = append(loadInfo.TypeErrors, typeErr)
@@ -290,6 +434,7 @@ func parseGoPackageByFree(pkg *Package, astPkg *ast.Package) error {
 	pkg.typePkg = typePkg
 	pkg.typInfo = typeInfo
 	pkg.typSize = &typeConf.Sizes
+	pkg.importer = typeConf.Importer
 
 	// 4. update the imported paths in the source files
 	var imports = make(map[string]bool)
@@ -317,7 +462,7 @@ func parseGoPackageByFree(pkg *Package, astPkg *ast.Package) error {
 
 // loadGoDirectoryByFree 'freely' loads the source files in this go directory,
 // not including those in its recursive children.
-func loadGoDirectoryByFree(goDir string) ([]*Package, error) {
+func loadGoDirectoryByFree(goDir string, opts *LoadOptions) ([]*Package, error) {
 	// 1. validate the input directory
 	goDirPath, _ := filepath.Abs(goDir)
 	fileInfo, err := os.Stat(goDirPath)
@@ -328,52 +473,22 @@ func loadGoDirectoryByFree(goDir string) ([]*Package, error) {
 		return nil, fmt.Errorf("not directory: %s", goDirPath)
 	}
 
-	// 2. parse the source files in dir
-	fileSet := token.NewFileSet()
-	pkgs, parseErr := parser.
-		ParseDir(fileSet, goDirPath, nil, parser.ParseComments)
-	if parseErr != nil {
-		return nil, parseErr
-	}
-	if len(pkgs) == 0 {
-		return nil, fmt.Errorf("no go files in: %s", goDirPath)
-	}
-
-	// 3. get the program and module info
-	var newPackages []*Package
+	// 2. get the program and module info
 	program, modErr := initProgram(goDirPath)
-	if modErr == nil && program != nil && program.module != nil {
-		pkgPath, pkgName, _, findErr := inferGoPkgInfo(program.module, goDirPath)
-		if findErr != nil {
-			return nil, fmt.Errorf("can't infer package path: %s", goDirPath)
-		}
-		for pkgKey, astPkg := range pkgs {
-			if len(pkgKey) > 0 && astPkg != nil && len(astPkg.Files) > 0 {
-				newPkgPath := pkgPath
-				if pkgKey != pkgName {
-					newPkgPath = fmt.Sprintf("%s/%s", pkgPath, pkgKey)
-				}
-				pkg := program.newPackage(pkgKey, newPkgPath, goDirPath)
-				if pkg != nil {
-					pkg.fileSet = fileSet
-					loadErr := parseGoPackageByFree(pkg, astPkg)
-					if loadErr == nil {
-						newPackages = append(newPackages, pkg)
-					}
-				}
-			}
-		}
-		return newPackages, nil
+	if modErr != nil || program == nil || program.module == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoGoMod, goDirPath)
 	}
 
-	// 4. cannot find go mod
-	return nil, fmt.Errorf("can't find go.mod in: %s", goDirPath)
+	// 3. load the packages in directory
+	program.options = opts
+	return program.loadDirectory(goDirPath, opts, true)
 }
 
 // loadAllDirectoriesByFree freely load the source files and their packages in
 // the root-directory as given. A 'go.mod' is required in rootDir or any of its
-// parent directories, or none is returned.
-func loadAllDirectoriesByFree(rootDir string) ([]*Package, error) {
+// parent directories, or none is returned. The packages loaded are returned even
+// if some directories fail, along with their DirError joined.
+func loadAllDirectoriesByFree(rootDir string, opts *LoadOptions) ([]*Package, error) {
 	// 1. validate the input directory
 	rootDirPath, _ := filepath.Abs(rootDir)
 	fileInfo, err := os.Stat(rootDirPath)
@@ -385,59 +500,271 @@ func loadAllDirectoriesByFree(rootDir string) ([]*Package, error) {
 	}
 
 	// 2. get the go.mod and module info
-	fileSet := token.NewFileSet()
 	program, modErr := initProgram(rootDirPath)
 	if modErr != nil {
 		return nil, modErr
 	}
 	if program == nil || program.module == nil {
-		return nil, fmt.Errorf("no go.mod is found: %s", rootDir)
+		return nil, fmt.Errorf("%w: %s", ErrNoGoMod, rootDir)
 	}
 
-	// 3. construct the mapping from Package to ast.Package for parsing
+	// 3. load the packages in each directory sharing program's FileSet
+	pkgs, dirErrs := program.loadAllDirectories(rootDirPath, opts)
+	return pkgs, errors.Join(dirErrs...)
+}
+
+// DirError is the failure of loading the packages in a directory, which is skipped by the loaders
+// of multiple directories. Use errors.As to find the directory and errors.Is to check the cause.
+type DirError struct {
+	DirPath string // DirPath is the absolute path of the directory skipped
+	Err     error  // Err is the cause why the directory is skipped
+}
+
+// Error describes the directory and the cause.
+func (dirErr *DirError) Error() string {
+	return fmt.Sprintf("skip %s: %v", dirErr.DirPath, dirErr.Err)
+}
+
+// Unwrap returns the cause why the directory is skipped.
+func (dirErr *DirError) Unwrap() error {
+	return dirErr.Err
+}
+
+// loadAllDirectories loads the packages in the directories (including the root) under rootDirPath
+// into the program in order of the directory paths, skipping those failed to be loaded, which are
+// returned as DirError in order. The source files of all directories are parsed concurrently before
+// the packages are type-checked in order.
+func (prog *Program) loadAllDirectories(rootDirPath string, opts *LoadOptions) ([]*Package, []error) {
+	prog.options = opts
+	var pkgDirs []string
+	for pkgDir, goFiles := range findPackagesAndGoFiles(rootDirPath, opts) {
+		if len(pkgDir) > 0 && len(goFiles) > 0 {
+			pkgDirs = append(pkgDirs, pkgDir)
+		}
+	}
+	sort.Strings(pkgDirs)
+	prog.parseDirectories(pkgDirs, opts)
 	var newPackages []*Package
-	for pkgDir, goFiles := range findPackagesAndGoFiles(rootDirPath) {
-		if len(pkgDir) == 0 || len(goFiles) == 0 {
+	var dirErrs []error
+	for index, pkgDir := range pkgDirs {
+		var event = LoadEvent{DirPath: pkgDir, Count: index + 1, Total: len(pkgDirs)}
+		dirPackages, loadErr := prog.loadDirectory(pkgDir, opts, !opts.excludesTests())
+		if loadErr != nil {
+			dirErrs = append(dirErrs, &DirError{DirPath: pkgDir, Err: loadErr})
+			event.Kind, event.Err = LoadEventDirSkipped, loadErr
+			opts.emit(event)
 			continue
 		}
+		for _, pkg := range dirPackages {
+			if pkg.loadInfo != nil && pkg.loadInfo.IllTyped {
+				event.Kind, event.PkgPath = LoadEventTypeCheckFailed, pkg.pkgPath
+				event.Err = errors.Join(pkg.loadInfo.TypeErrors...)
+				opts.emit(event)
+			}
+		}
+		event.Kind, event.PkgPath, event.Err = LoadEventPackageLoaded, "", nil
+		if len(dirPackages) > 0 {
+			event.PkgPath = dirPackages[0].pkgPath
+		}
+		opts.emit(event)
+		newPackages = append(newPackages, dirPackages...)
+	}
+	return newPackages, dirErrs
+}
 
-		astPkgs, parseErr := parser.ParseDir(fileSet, pkgDir, nil, parser.ParseComments)
-		if parseErr != nil || astPkgs == nil || len(astPkgs) == 0 {
-			continue
+// parseDirectories parses the source files in directories concurrently by a bounded pool (GOMAXPROCS
+// or Concurrency in options) of goroutines, and records the syntax in program for the later loading
+// of the directories, while the directories failed to be parsed are left to loadDirectory to report
+// errors.
+//
+// All workers share the program's FileSet, since token.FileSet synchronizes AddFile internally, so
+// that each Package still positions its files by the program's FileSet. Only the order of files in
+// FileSet (i.e. their bases) depends on scheduling, which doesn't change the resolved positions.
+// The type checking is kept serial, since the importer loads the imported packages on demand.
+func (prog *Program) parseDirectories(dirPaths []string, opts *LoadOptions) {
+	// 1. collect the directories not parsed yet
+	var pendingDirs []string
+	for _, dirPath := range dirPaths {
+		if _, ok := prog.parsedDirs[dirPath]; !ok {
+			pendingDirs = append(pendingDirs, dirPath)
 		}
+	}
 
-		pkgPath, pkgName, _, pkgErr := inferGoPkgInfo(program.module, pkgDir)
-		if pkgErr != nil {
+	// 2. parse the directories in the bounded pool
+	var parsedDirs = make([]*parsedDir, len(pendingDirs))
+	var indices = make(chan int)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < opts.concurrency() && worker < len(pendingDirs); worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range indices {
+				dir, parseErr := parseGoDirectory(prog.fileSet, pendingDirs[index], opts)
+				if parseErr == nil && len(dir.astPkgs) > 0 {
+					parsedDirs[index] = dir
+				}
+			}
+		}()
+	}
+	for index := range pendingDirs {
+		opts.emit(LoadEvent{
+			Kind:    LoadEventParsingDir,
+			DirPath: pendingDirs[index],
+			Count:   index + 1,
+			Total:   len(pendingDirs),
+		})
+		indices <- index
+	}
+	close(indices)
+	waitGroup.Wait()
+
+	// 3. record the parsed syntax in the program
+	for index, dir := range parsedDirs {
+		if dir == nil {
 			continue
 		}
+		if prog.parsedDirs == nil {
+			prog.parsedDirs = make(map[string]*parsedDir)
+		}
+		prog.parsedDirs[pendingDirs[index]] = dir
+	}
+}
 
-		for pkgKey, astPkg := range astPkgs {
-			if len(pkgKey) > 0 && astPkg != nil && len(astPkg.Files) > 0 {
-				newPkgPath := pkgPath
-				if pkgKey != pkgName {
-					newPkgPath = fmt.Sprintf("%s/%s", pkgPath, pkgKey)
-				}
-				pkg := program.newPackage(pkgKey, newPkgPath, pkgDir)
-				if pkg != nil {
-					pkg.fileSet = fileSet
-					loadErr := parseGoPackageByFree(pkg, astPkg)
-					if loadErr == nil {
-						newPackages = append(newPackages, pkg)
-					}
-				}
+// parseDirectory parses the source files in directory using the FileSet of program, or returns the
+// syntax parsed before (or the packages built from it), such that no source file is parsed twice in
+// the program.
+func (prog *Program) parseDirectory(dirPath string, opts *LoadOptions) (*parsedDir, error) {
+	if dir, ok := prog.parsedDirs[dirPath]; ok {
+		return dir, nil
+	}
+	dir, parseErr := parseGoDirectory(prog.fileSet, dirPath, opts)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if len(dir.astPkgs) == 0 {
+		return nil, fmt.Errorf("%w in: %s", ErrNoPackages, dirPath)
+	}
+	if prog.parsedDirs == nil {
+		prog.parsedDirs = make(map[string]*parsedDir)
+	}
+	prog.parsedDirs[dirPath] = dir
+	return dir, nil
+}
+
+// loadDirectory freely loads the packages of source files in the directory into the program using
+// its FileSet, while the packages loaded before (e.g. being imported by other packages) are reused.
+//
+// The packages are type-checked in the order of their names, where the external test packages are
+// the last ones (or skipped if withTests is false, e.g. when the directory is loaded for import),
+// so that the imports of test packages could be resolved to the loaded package under test.
+func (prog *Program) loadDirectory(dirPath string, opts *LoadOptions, withTests bool) ([]*Package, error) {
+	// 1. parse the source files in the directory
+	dir, parseErr := prog.parseDirectory(dirPath, opts)
+	if parseErr != nil {
+		return nil, parseErr
+	} else if dir.builtIn != nil {
+		return dir.builtIn, nil
+	}
+	pkgPath, pkgName, _, findErr := inferGoPkgInfo(prog.module, dirPath)
+	if findErr != nil {
+		return nil, fmt.Errorf("can't infer package path: %s", dirPath)
+	}
+
+	// 2. sort the package names with test packages at last
+	var pkgKeys []string
+	for pkgKey, astPkg := range dir.astPkgs {
+		if len(pkgKey) > 0 && astPkg != nil && len(astPkg.Files) > 0 {
+			if withTests || !strings.HasSuffix(pkgKey, "_test") {
+				pkgKeys = append(pkgKeys, pkgKey)
 			}
 		}
 	}
+	sort.Slice(pkgKeys, func(i, j int) bool {
+		iTest := strings.HasSuffix(pkgKeys[i], "_test")
+		jTest := strings.HasSuffix(pkgKeys[j], "_test")
+		if iTest != jTest {
+			return jTest
+		}
+		return pkgKeys[i] < pkgKeys[j]
+	})
+
+	// 3. decide the primary package and its external test
+	var primaryName = primaryPkgNameOf(dir, pkgName)
+	var pkgPathOf = func(pkgKey string) string {
+		if pkgKey == primaryName {
+			return pkgPath
+		} else if len(primaryName) > 0 && pkgKey == primaryName+"_test" {
+			return pkgPath + "_test" // the external test package as named by go list
+		}
+		return fmt.Sprintf("%s/%s", pkgPath, pkgKey)
+	}
+
+	// 4. type-check the packages not loaded in program
+	var newPackages []*Package
+	for _, pkgKey := range pkgKeys {
+		newPkgPath := pkgPathOf(pkgKey)
+		if pkg := prog.Package(newPkgPath); pkg.IsLoaded() && !pkg.isCached() {
+			newPackages = append(newPackages, pkg)
+			continue
+		}
+		pkg := prog.newPackage(pkgKey, newPkgPath, dirPath)
+		if pkg != nil {
+			if pkg.isCached() {
+				// the files and imports restored from cache are replaced by those re-parsed
+				pkg.srcFiles, pkg.imports = make(map[string]*SrcFile), nil
+			}
+			pkg.fileSet = prog.fileSet
+			loadErr := parseGoPackageByFree(pkg, dir, opts)
+			recordIgnoredFiles(pkg, dir)
+			if loadErr == nil {
+				newPackages = append(newPackages, pkg)
+			}
+		}
+	}
+
+	// 5. link the primary package to its external test
+	if len(primaryName) > 0 {
+		primaryPkg := prog.Package(pkgPathOf(primaryName))
+		testPkg := prog.Package(pkgPathOf(primaryName + "_test"))
+		if primaryPkg != nil && testPkg.IsLoaded() {
+			primaryPkg.testPkg = testPkg
+		}
+	}
+
+	// 6. release the syntax and sources once all packages are built
+	if len(newPackages) == len(dir.astPkgs) {
+		prog.parsedDirs[dirPath] = &parsedDir{builtIn: newPackages}
+	}
 	return newPackages, nil
 }
 
-// findPackagesAndGoFiles return a map from directory to the go files included.
-func findPackagesAndGoFiles(rootDir string) map[string][]string {
+// primaryPkgNameOf returns the name of primary (non-test) package in the directory, which is the only
+// package other than the tests, or the one named as the directory if there are many. It returns the
+// name of directory if there is no primary package, e.g. only the external tests in directory.
+func primaryPkgNameOf(dir *parsedDir, dirName string) string {
+	var pkgNames []string
+	for pkgKey := range dir.astPkgs {
+		if !strings.HasSuffix(pkgKey, "_test") {
+			pkgNames = append(pkgNames, pkgKey)
+		}
+	}
+	if len(pkgNames) == 1 {
+		return pkgNames[0]
+	}
+	return dirName
+}
+
+// findPackagesAndGoFiles return a map from directory to the go files included,
+// where the sub-directories skipped by options (e.g. testdata) are excluded.
+func findPackagesAndGoFiles(rootDir string, opts *LoadOptions) map[string][]string {
 	var goFiles []string
 	_ = filepath.Walk(rootDir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if info.IsDir() && path != rootDir && opts.SkipDir(info.Name()) {
+			return filepath.SkipDir
+		}
 		if !info.IsDir() && strings.HasSuffix(path, ".go") {
 			goFiles = append(goFiles, path)
 		}
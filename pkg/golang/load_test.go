@@ -0,0 +1,442 @@
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testModulePath is the module path of the modules written by writeModule.
+const testModulePath = "example.com/m"
+
+// writeModule writes the files (mapped from slash-separated paths relative to root) into a temporary
+// directory as a module, where go.mod declaring testModulePath is added if not given, and returns
+// the absolute path of the root.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	rootDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files[GoModFileName]; !ok {
+		files[GoModFileName] = "module " + testModulePath + "\n\ngo 1.20\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(rootDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rootDir
+}
+
+// mustPackage returns the package of path in program, or fails the test if it is not loaded.
+func mustPackage(t *testing.T, prog *Program, pkgPath string) *Package {
+	t.Helper()
+	pkg := prog.Package(pkgPath)
+	if !pkg.IsLoaded() {
+		t.Fatalf("package %s is not loaded", pkgPath)
+	}
+	return pkg
+}
+
+// baseNamesOf returns the sorted base names of the paths.
+func baseNamesOf(paths []string) []string {
+	var names = make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// panicImporter panics in importing the packages out of GOROOT, which crafts the panics in checking.
+type panicImporter struct{}
+
+func (panicImporter) Import(path string) (*types.Package, error) {
+	if strings.Contains(path, ".") {
+		panic("crafted panic importing " + path)
+	}
+	return fakeImporter(nil).Import(path)
+}
+
+// isPanicTypeError checks whether the load info records the panic in type checking as type error.
+func isPanicTypeError(info *LoadInfo) bool {
+	if info == nil || !info.IllTyped {
+		return false
+	}
+	for _, typeErr := range info.TypeErrors {
+		if strings.Contains(typeErr.Error(), "crafted panic") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTypeCheckPanicRecovered(t *testing.T) {
+	const code = "package p\n\nimport \"example.com/ext\"\n\nvar _ = ext.N\n"
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go":                          code,
+		"q/q.go":                          "package q\n\nimport \"example.com/vend\"\n\nvar _ = vend.N\n",
+		"vendor/example.com/vend/vend.go": code,
+		"vendor/modules.txt":              "",
+	})
+	var opts = &LoadOptions{Importer: panicImporter{}}
+
+	t.Run("directory", func(t *testing.T) {
+		prog, _ := NewDefaultProgram(rootDir, opts)
+		if info := mustPackage(t, prog, testModulePath+"/p").LoadInfo(); !isPanicTypeError(info) {
+			t.Errorf("type errors = %v, want the panic", info.AllErrors())
+		}
+		if info := mustPackage(t, prog, testModulePath+"/q").LoadInfo(); isPanicTypeError(info) {
+			t.Errorf("panic in the vendored package escapes to its importer: %v", info.AllErrors())
+		}
+	})
+	t.Run("source file", func(t *testing.T) {
+		srcFile, err := loadSourceFileByFree(filepath.Join(rootDir, "p", "p.go"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info := srcFile.Package().LoadInfo(); !isPanicTypeError(info) {
+			t.Errorf("type errors = %v, want the panic", info.AllErrors())
+		}
+	})
+	t.Run("virtual file", func(t *testing.T) {
+		var pkg = newPackage(nil, "p", "p", "")
+		pkg.options = opts
+		if _, err := pkg.AddVirtualFile("p.go", code); err != nil {
+			t.Fatal(err)
+		}
+		if info := pkg.LoadInfo(); !isPanicTypeError(info) {
+			t.Errorf("type errors = %v, want the panic", info.AllErrors())
+		}
+	})
+}
+
+func TestLoadEvaluatesBuildConstraints(t *testing.T) {
+	legacyDir := writeModule(t, map[string]string{
+		"p/common.go":       "package p\n",
+		"p/legacy.go":       "// +build windows\n\npackage p\n\nconst Legacy = true\n",
+		"p/legacy_other.go": "// +build !windows\n\npackage p\n\nconst Legacy = false\n",
+	})
+	for _, test := range []struct {
+		rootDir string
+		pkgPath string
+		goos    string
+		loaded  []string
+		ignored []string
+	}{
+		{
+			rootDir: filepath.Join("testdata", "buildtags"),
+			pkgPath: "example.com/buildtags/p",
+			goos:    "linux",
+			loaded:  []string{"common.go", "only_linux.go"},
+			ignored: []string{"name_windows.go", "only_windows.go"},
+		},
+		{
+			rootDir: filepath.Join("testdata", "buildtags"),
+			pkgPath: "example.com/buildtags/p",
+			goos:    "windows",
+			loaded:  []string{"common.go", "name_windows.go", "only_windows.go"},
+			ignored: []string{"only_linux.go"},
+		},
+		{
+			rootDir: legacyDir,
+			pkgPath: testModulePath + "/p",
+			goos:    "linux",
+			loaded:  []string{"common.go", "legacy_other.go"},
+			ignored: []string{"legacy.go"},
+		},
+		{
+			rootDir: legacyDir,
+			pkgPath: testModulePath + "/p",
+			goos:    "windows",
+			loaded:  []string{"common.go", "legacy.go"},
+			ignored: []string{"legacy_other.go"},
+		},
+	} {
+		prog, err := Load(test.rootDir, WithGOOS(test.goos), WithGOARCH("amd64"))
+		if err != nil {
+			t.Fatalf("%s on %s: %v", test.rootDir, test.goos, err)
+		}
+		pkg := mustPackage(t, prog, test.pkgPath)
+		if pkg.LoadInfo().HasErrors() {
+			t.Errorf("%s on %s: %v", test.pkgPath, test.goos, pkg.LoadInfo().AllErrors())
+		}
+		if got := baseNamesOf(pkg.LoadInfo().LoadedFiles); !reflect.DeepEqual(got, test.loaded) {
+			t.Errorf("%s on %s: LoadedFiles = %v, want %v", test.pkgPath, test.goos, got, test.loaded)
+		}
+		if got := baseNamesOf(pkg.LoadInfo().IgnoredFiles); !reflect.DeepEqual(got, test.ignored) {
+			t.Errorf("%s on %s: IgnoredFiles = %v, want %v", test.pkgPath, test.goos, got, test.ignored)
+		}
+	}
+}
+
+func TestLoadWithOverlay(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go": "package p\n\nfunc P() int { return 1 \n",
+	})
+	var pPath = filepath.Join(rootDir, "p", "p.go")
+	var qPath = filepath.Join(rootDir, "p", "q.go")
+	if _, err := Load(rootDir); err == nil {
+		t.Fatalf("Load(%s) = nil, want syntax error on disk", rootDir)
+	}
+
+	// the overlay fixes the syntax error on disk and adds a file only in memory
+	var fixed = "package p\n\nfunc P() int { return Q() }\n"
+	var opts = newLoadOptions(WithOverlay(map[string][]byte{
+		pPath: []byte(fixed),
+		qPath: []byte("package p\n\nfunc Q() int { return 2 }\n"),
+	}))
+	prog, err := NewDefaultProgram(rootDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, testModulePath+"/p")
+	if pkg.LoadInfo().HasErrors() {
+		t.Fatalf("overlay package is ill-typed: %v", pkg.LoadInfo().AllErrors())
+	}
+	if got := baseNamesOf(pkg.GoFiles()); !reflect.DeepEqual(got, []string{"p.go", "q.go"}) {
+		t.Errorf("GoFiles = %v, want [p.go q.go]", got)
+	}
+	srcFile := pkg.SrcFile(pPath)
+	if srcFile.Code() != fixed {
+		t.Errorf("Code = %q, want the overlay %q", srcFile.Code(), fixed)
+	}
+	decl, ok := srcFile.Syntax().Decls[0].(*ast.FuncDecl)
+	if !ok || len(decl.Body.List) != 1 {
+		t.Fatalf("syntax tree does not reflect the overlay: %#v", srcFile.Syntax().Decls[0])
+	}
+	if call, ok := decl.Body.List[0].(*ast.ReturnStmt).Results[0].(*ast.CallExpr); !ok ||
+		call.Fun.(*ast.Ident).Name != "Q" {
+		t.Errorf("P returns %#v, want the call of Q in overlay", decl.Body.List[0])
+	}
+
+	// the single file is loaded from the overlay as well
+	srcFile, err = loadSourceFileByFree(pPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcFile.Code() != fixed || srcFile.Syntax() == nil {
+		t.Errorf("single file Code = %q, want the overlay %q", srcFile.Code(), fixed)
+	}
+}
+
+func TestLoadResolvesVendoredImports(t *testing.T) {
+	rootDir, err := filepath.Abs(filepath.Join("testdata", "vendored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, "example.com/vendored/app")
+	if pkg.LoadInfo().HasErrors() {
+		t.Fatalf("app does not type-check against vendored dep: %v", pkg.LoadInfo().AllErrors())
+	}
+	var dep *types.Package
+	for _, imported := range pkg.TypePkg().Imports() {
+		if imported.Path() == "example.com/dep" {
+			dep = imported
+		}
+	}
+	if dep == nil || dep.Scope().Lookup("Vendored") == nil {
+		t.Fatalf("example.com/dep is not imported from vendor: %v", dep)
+	}
+	for _, loaded := range prog.AllPackages() {
+		if strings.Contains(loaded.DirPath(), string(filepath.Separator)+"vendor"+string(filepath.Separator)) {
+			t.Errorf("vendored package %s is loaded as package of module", loaded.PkgPath())
+		}
+	}
+}
+
+func TestLoadSeparatesExternalTests(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"foo/foo.go":               "package foo\n\nfunc Foo() int { return 1 }\n",
+		"foo/foo_internal_test.go": "package foo\n\nvar internal = Foo()\n",
+		"foo/foo_external_test.go": "package foo_test\n\nimport \"example.com/m/foo\"\n\nvar external = foo.Foo()\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkg, testPkg = mustPackage(t, prog, testModulePath+"/foo"), mustPackage(t, prog, testModulePath+"/foo_test")
+	if pkg.PkgName() != "foo" || testPkg.PkgName() != "foo_test" || pkg.DirPath() != testPkg.DirPath() {
+		t.Fatalf("packages = %s in %s and %s in %s", pkg.PkgName(), pkg.DirPath(), testPkg.PkgName(), testPkg.DirPath())
+	}
+	if pkg.TestPackage() != testPkg || testPkg.TestPackage() != nil {
+		t.Errorf("TestPackage = %v and %v, want the external test package and nil", pkg.TestPackage(), testPkg.TestPackage())
+	}
+	if got, want := baseNamesOf(pkg.GoFiles()), []string{"foo.go", "foo_internal_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files of foo = %v, want %v", got, want)
+	}
+	if got, want := baseNamesOf(testPkg.GoFiles()), []string{"foo_external_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files of foo_test = %v, want %v", got, want)
+	}
+	for _, loaded := range []*Package{pkg, testPkg} {
+		if loaded.LoadInfo().HasErrors() {
+			t.Errorf("%s: %v", loaded.PkgPath(), loaded.LoadInfo().AllErrors())
+		}
+	}
+	if pkg.TypePkg().Scope().Lookup("internal") == nil {
+		t.Errorf("internal test file is not checked with package foo")
+	}
+}
+
+func TestLoadParsesDirectoriesConcurrently(t *testing.T) {
+	const dirCount = 50
+	var files = make(map[string]string)
+	for index := 0; index < dirCount; index++ {
+		name := fmt.Sprintf("d%02d", index)
+		code := fmt.Sprintf("package %s\n\nfunc F() int { return %d }\n", name, index)
+		if index > 0 {
+			prev := fmt.Sprintf("d%02d", index-1)
+			code = fmt.Sprintf("package %s\n\nimport \"%s/%s\"\n\nfunc F() int { return %s.F() + 1 }\n",
+				name, testModulePath, prev, prev)
+		}
+		files[name+"/"+name+".go"] = code
+	}
+	rootDir := writeModule(t, files)
+	pkgs, err := loadAllDirectoriesByFree(rootDir, &LoadOptions{Concurrency: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != dirCount {
+		t.Fatalf("loaded %d packages, want %d", len(pkgs), dirCount)
+	}
+	for _, pkg := range pkgs {
+		if pkg.LoadInfo().HasErrors() {
+			t.Errorf("%s: errors in loading = %v", pkg.PkgPath(), pkg.LoadInfo().AllErrors())
+		}
+		for _, path := range pkg.GoFiles() {
+			position := pkg.FileSet().Position(pkg.SrcFile(path).Syntax().Package)
+			if position.Filename != path || position.Line != 1 {
+				t.Errorf("%s: package clause is positioned at %s", path, position)
+			}
+		}
+	}
+}
+
+func TestLoadReleasesParsedDirectories(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\nimport \"example.com/m/b\"\n\nvar A = b.B\n",
+		"b/b.go":      "package b\n\nvar B int\n",
+		"b/b_test.go": "package b_test\n\nimport \"example.com/m/b\"\n\nvar _ = b.B\n",
+	})
+	pkgs, err := loadGoDirectoryByFree(filepath.Join(rootDir, "a"), nil)
+	if err != nil || len(pkgs) != 1 {
+		t.Fatalf("loading a = %v, %v", pkgs, err)
+	}
+	var prog = pkgs[0].Program()
+	var bDir = filepath.Join(rootDir, "b")
+
+	// the syntax of b_test is kept since only b is built in importing it
+	if dir := prog.parsedDirs[bDir]; dir == nil || dir.builtIn != nil || len(dir.sources) != 2 {
+		t.Fatalf("parsed b = %+v, want the syntax of b and b_test", dir)
+	}
+	if _, err := prog.loadDirectory(bDir, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	var b, bTest = prog.Package(testModulePath + "/b"), prog.Package(testModulePath + "/b_test")
+	if bTest.LoadInfo().IllTyped || b.TestPackage() != bTest {
+		t.Errorf("TestPackage of b = %v, want %v", b.TestPackage(), bTest)
+	}
+	var parsedCount int
+	prog.FileSet().Iterate(func(file *token.File) bool {
+		if filepath.Base(file.Name()) == "b.go" {
+			parsedCount++
+		}
+		return true
+	})
+	if parsedCount != 1 {
+		t.Errorf("b.go is parsed %d times, want once", parsedCount)
+	}
+
+	// the syntax and sources are released once all packages in directories are built
+	for dirPath, dir := range prog.parsedDirs {
+		if dir.astPkgs != nil || dir.sources != nil || len(dir.builtIn) == 0 {
+			t.Errorf("%s: parsed directory is not released: %+v", dirPath, dir)
+		}
+	}
+	if again, err := prog.loadDirectory(bDir, nil, true); err != nil || len(again) != 2 {
+		t.Errorf("loading b again = %v, %v, want b and b_test", again, err)
+	}
+}
+
+func TestLoadSkipsTestdataAndHiddenDirs(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":            "package a\n\nconst A = 1\n",
+		"a/testdata/x/x.go": "package x\n\nconst X = 1\n",
+		"testdata/t/t.go":   "package t\n\nconst T = 1\n",
+		".hidden/h/h.go":    "package h\n\nconst H = 1\n",
+	})
+	for _, test := range []struct {
+		opts     *LoadOptions
+		pkgPaths []string
+	}{
+		{nil, []string{"example.com/m/a"}},
+		{&LoadOptions{IncludeTestdata: true}, []string{"example.com/m/a", "example.com/m/a/testdata/x", "example.com/m/testdata/t"}},
+		{&LoadOptions{IncludeHidden: true}, []string{"example.com/m/.hidden/h", "example.com/m/a"}},
+	} {
+		pkgs, _ := loadAllDirectoriesByFree(rootDir, test.opts)
+		var pkgPaths []string
+		for _, pkg := range pkgs {
+			pkgPaths = append(pkgPaths, pkg.PkgPath())
+		}
+		sort.Strings(pkgPaths)
+		if !reflect.DeepEqual(pkgPaths, test.pkgPaths) {
+			t.Errorf("packages with %+v = %v, want %v", test.opts, pkgPaths, test.pkgPaths)
+		}
+	}
+
+	for dirName, skipped := range map[string]bool{
+		VendorDirName: true, TestdataDirName: true, ".git": true, ".": false, "src": false,
+	} {
+		if got := (*LoadOptions)(nil).SkipDir(dirName); got != skipped {
+			t.Errorf("SkipDir(%q) = %v, want %v", dirName, got, skipped)
+		}
+	}
+	if (&LoadOptions{IncludeVendor: true}).SkipDir(VendorDirName) {
+		t.Errorf("SkipDir(vendor) with IncludeVendor = true, want false")
+	}
+}
+
+func TestLoadReturnsPartialResults(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"good/good.go":     "package good\n\nconst Good = 1\n",
+		"broken/broken.go": "package broken\n\nfunc Broken( {\n",
+	})
+	pkgs, err := loadAllDirectoriesByFree(rootDir, nil)
+	if len(pkgs) != 1 || pkgs[0].PkgPath() != testModulePath+"/good" || pkgs[0].LoadInfo().HasErrors() {
+		t.Fatalf("packages = %v, want the good one only", pkgs)
+	}
+	var dirErr *DirError
+	if !errors.As(err, &dirErr) {
+		t.Fatalf("error = %v, want DirError", err)
+	}
+	var brokenDir = filepath.Join(rootDir, "broken")
+	if dirErr.DirPath != brokenDir || dirErr.Unwrap() == nil ||
+		!strings.Contains(dirErr.Unwrap().Error(), "broken.go") {
+		t.Errorf("DirError = %+v, want the parse error of %s", dirErr, brokenDir)
+	}
+	if !strings.HasPrefix(err.Error(), "skip "+brokenDir+": ") {
+		t.Errorf("error = %q, want skipping %s", err, brokenDir)
+	}
+
+	pkgs, err = loadAllDirectoriesByFree(filepath.Join(rootDir, "good"), nil)
+	if err != nil || len(pkgs) != 1 {
+		t.Errorf("loading good = %v, %v, want the package without error", pkgs, err)
+	}
+}
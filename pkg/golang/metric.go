@@ -0,0 +1,270 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the metrics of source code in SrcFile, Package and Program, such
+// as the cyclomatic complexity of functions and the lines of code and comments.
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// The weights and limits of FileComplexity.Score, which is computed as:
+//
+//	maxScore     = 100 * (1 - (MaxCyclomatic - 1) / (ScoreMaxCyclomaticLimit - 1))
+//	meanScore    = 100 * (1 - (MeanCyclomatic - 1) / (ScoreMeanCyclomaticLimit - 1))
+//	commentScore = 100 * CommentRatio / ScoreCommentRatioTarget
+//	Score        = ScoreWeightMax * maxScore + ScoreWeightMean * meanScore +
+//	               ScoreWeightComment * commentScore
+//
+// where each partial score is clamped into [0, 100], and the complexity scores are 100 if there is
+// no function. So a file scores 100 if all functions are straight-line and a fifth are comments.
+const (
+	ScoreWeightMax           = 0.4  // ScoreWeightMax is the weight of maximal function complexity
+	ScoreWeightMean          = 0.4  // ScoreWeightMean is the weight of mean function complexity
+	ScoreWeightComment       = 0.2  // ScoreWeightComment is the weight of comment ratio
+	ScoreMaxCyclomaticLimit  = 30.0 // ScoreMaxCyclomaticLimit scores 0 for the maximal complexity
+	ScoreMeanCyclomaticLimit = 15.0 // ScoreMeanCyclomaticLimit scores 0 for the mean complexity
+	ScoreCommentRatioTarget  = 0.2  // ScoreCommentRatioTarget scores 100 for the comment ratio
+)
+
+// FileComplexity aggregates the complexity metrics of functions and the lines in source code.
+type FileComplexity struct {
+	Functions       int     // Functions is the number of function declarations
+	TotalCyclomatic int     // TotalCyclomatic is the sum of cyclomatic complexity of functions
+	MaxCyclomatic   int     // MaxCyclomatic is the maximal cyclomatic complexity of one function
+	LOC             int     // LOC is the number of lines in the source code
+	CommentLines    int     // CommentLines is the number of lines containing comments
+	CommentRatio    float64 // CommentRatio is the ratio of comment lines to all lines
+}
+
+// MeanCyclomatic is the mean cyclomatic complexity of functions, or 0 if there is no function.
+func (complexity FileComplexity) MeanCyclomatic() float64 {
+	if complexity.Functions == 0 {
+		return 0
+	}
+	return float64(complexity.TotalCyclomatic) / float64(complexity.Functions)
+}
+
+// clampScore clamps the partial score into [0, 100].
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	} else if score > 100 {
+		return 100
+	}
+	return score
+}
+
+// Score combines the metrics into a score in [0, 100] where higher is better (less complex), as the
+// formula with weights documented above the ScoreWeightMax.
+func (complexity FileComplexity) Score() float64 {
+	var maxScore, meanScore float64 = 100, 100
+	if complexity.Functions > 0 {
+		maxScore = 100 * (1 - float64(complexity.MaxCyclomatic-1)/(ScoreMaxCyclomaticLimit-1))
+		meanScore = 100 * (1 - (complexity.MeanCyclomatic()-1)/(ScoreMeanCyclomaticLimit-1))
+	}
+	commentScore := 100 * complexity.CommentRatio / ScoreCommentRatioTarget
+	return ScoreWeightMax*clampScore(maxScore) + ScoreWeightMean*clampScore(meanScore) +
+		ScoreWeightComment*clampScore(commentScore)
+}
+
+// add accumulates the metrics of the other into this complexity, of which ratio is recomputed.
+func (complexity *FileComplexity) add(other FileComplexity) {
+	complexity.Functions += other.Functions
+	complexity.TotalCyclomatic += other.TotalCyclomatic
+	if other.MaxCyclomatic > complexity.MaxCyclomatic {
+		complexity.MaxCyclomatic = other.MaxCyclomatic
+	}
+	complexity.LOC += other.LOC
+	complexity.CommentLines += other.CommentLines
+	complexity.CommentRatio = 0
+	if complexity.LOC > 0 {
+		complexity.CommentRatio = float64(complexity.CommentLines) / float64(complexity.LOC)
+	}
+}
+
+// cyclomaticOf computes the cyclomatic complexity of the function as 1 plus the number of decision
+// points in its body (including the function literals): if, for, range, non-default case and comm
+// clauses, and the && and || operators.
+func cyclomaticOf(funcDecl *ast.FuncDecl) int {
+	var complexity = 1
+	if funcDecl.Body == nil {
+		return complexity
+	}
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		switch stmt := node.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if stmt.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if stmt.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if stmt.Op == token.LAND || stmt.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// countLines returns the number of lines in the code, in which the last line is counted even if it
+// doesn't end with a new line.
+func countLines(code string) int {
+	if len(code) == 0 {
+		return 0
+	}
+	lines := strings.Count(code, NewLine)
+	if !strings.HasSuffix(code, NewLine) {
+		lines++
+	}
+	return lines
+}
+
+// Complexity computes the complexity metrics of the functions and lines in this source file. The
+// comment lines are only counted if syntax is loaded.
+func (file *SrcFile) Complexity() FileComplexity {
+	var complexity FileComplexity
+	if file == nil {
+		return complexity
+	}
+	for _, funcDecl := range file.Functions() {
+		cyclomatic := cyclomaticOf(funcDecl)
+		complexity.Functions++
+		complexity.TotalCyclomatic += cyclomatic
+		if cyclomatic > complexity.MaxCyclomatic {
+			complexity.MaxCyclomatic = cyclomatic
+		}
+	}
+	complexity.LOC = countLines(file.code)
+	if file.syntax != nil && file.pkg != nil && file.pkg.fileSet != nil {
+		var commentLines = make(map[int]bool)
+		for _, group := range file.syntax.Comments {
+			begLine := file.pkg.fileSet.Position(group.Pos()).Line
+			endLine := file.pkg.fileSet.Position(group.End()).Line
+			for line := begLine; line <= endLine; line++ {
+				commentLines[line] = true
+			}
+		}
+		complexity.CommentLines = len(commentLines)
+	}
+	if complexity.LOC > 0 {
+		complexity.CommentRatio = float64(complexity.CommentLines) / float64(complexity.LOC)
+	}
+	return complexity
+}
+
+// CommentCoverage counts the exported function declarations (including methods) in this source file
+// and those with doc comments, which are returned as counts such that they could be summed up.
+func (file *SrcFile) CommentCoverage() (commented, total int) {
+	for _, funcDecl := range file.Functions() {
+		if funcDecl.Name.IsExported() {
+			total++
+			if funcDecl.Doc != nil {
+				commented++
+			}
+		}
+	}
+	return commented, total
+}
+
+// CommentCoverage sums up the CommentCoverage of the source files in this package.
+func (pkg *Package) CommentCoverage() (commented, total int) {
+	if pkg == nil {
+		return 0, 0
+	}
+	for _, srcFile := range pkg.syntaxFiles() {
+		fileCommented, fileTotal := srcFile.CommentCoverage()
+		commented, total = commented+fileCommented, total+fileTotal
+	}
+	return commented, total
+}
+
+// PackageComplexity aggregates the complexity metrics of source files in a package, of which Score
+// is computed over the aggregated metrics.
+type PackageComplexity struct {
+	FileComplexity                           // FileComplexity is the metrics aggregated over files
+	Files          map[string]FileComplexity // Files map from paths of source files to their metrics
+}
+
+// Complexity computes the complexity metrics of each source file in this package and aggregates them.
+func (pkg *Package) Complexity() PackageComplexity {
+	var complexity = PackageComplexity{Files: make(map[string]FileComplexity)}
+	if pkg == nil {
+		return complexity
+	}
+	var paths = pkg.GoFiles()
+	sort.Strings(paths)
+	for _, path := range paths {
+		fileComplexity := pkg.srcFiles[path].Complexity()
+		complexity.Files[path] = fileComplexity
+		complexity.add(fileComplexity)
+	}
+	return complexity
+}
+
+// ProgramMetrics are the statistics aggregated over all packages in a program for a health overview.
+type ProgramMetrics struct {
+	PackageCount             int     // PackageCount is the number of packages in program
+	FileCount                int     // FileCount is the number of source files in packages
+	TotalLOC                 int     // TotalLOC is the number of lines in source files
+	TotalFunctions           int     // TotalFunctions is the number of function declarations
+	TotalTypes               int     // TotalTypes is the number of named types in package scopes
+	MeanCyclomaticComplexity float64 // MeanCyclomaticComplexity is the mean over all functions
+	MaxCyclomaticComplexity  int     // MaxCyclomaticComplexity is the maximum of all functions
+	TypeRatio                float64 // TypeRatio is the number of named types per function
+	IllTypedPackages         int     // IllTypedPackages is the number of ill-typed packages
+}
+
+// Metrics computes the metrics of each package in the program and aggregates them, where the named
+// types are only counted in the packages that are type-checked.
+func (prog *Program) Metrics() ProgramMetrics {
+	var metrics ProgramMetrics
+	var complexity FileComplexity
+	for _, pkg := range prog.AllPackages() {
+		pkgComplexity := pkg.Complexity()
+		complexity.add(pkgComplexity.FileComplexity)
+		metrics.PackageCount++
+		metrics.FileCount += len(pkgComplexity.Files)
+		metrics.TotalTypes += len(pkg.NamedTypes())
+		if pkg.loadInfo != nil && pkg.loadInfo.IllTyped {
+			metrics.IllTypedPackages++
+		}
+	}
+	metrics.TotalLOC = complexity.LOC
+	metrics.TotalFunctions = complexity.Functions
+	metrics.MeanCyclomaticComplexity = complexity.MeanCyclomatic()
+	metrics.MaxCyclomaticComplexity = complexity.MaxCyclomatic
+	if metrics.TotalFunctions > 0 {
+		metrics.TypeRatio = float64(metrics.TotalTypes) / float64(metrics.TotalFunctions)
+	}
+	return metrics
+}
+
+// String renders the metrics as a table of names and values with aligned columns.
+func (metrics ProgramMetrics) String() string {
+	var builder strings.Builder
+	var writer = tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "packages\t%d\n", metrics.PackageCount)
+	_, _ = fmt.Fprintf(writer, "files\t%d\n", metrics.FileCount)
+	_, _ = fmt.Fprintf(writer, "lines of code\t%d\n", metrics.TotalLOC)
+	_, _ = fmt.Fprintf(writer, "functions\t%d\n", metrics.TotalFunctions)
+	_, _ = fmt.Fprintf(writer, "named types\t%d\n", metrics.TotalTypes)
+	_, _ = fmt.Fprintf(writer, "mean cyclomatic complexity\t%.2f\n", metrics.MeanCyclomaticComplexity)
+	_, _ = fmt.Fprintf(writer, "max cyclomatic complexity\t%d\n", metrics.MaxCyclomaticComplexity)
+	_, _ = fmt.Fprintf(writer, "types per function\t%.2f\n", metrics.TypeRatio)
+	_, _ = fmt.Fprintf(writer, "ill-typed packages\t%d\n", metrics.IllTypedPackages)
+	_ = writer.Flush()
+	return builder.String()
+}
@@ -0,0 +1,117 @@
+package golang
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSrcFileComplexity(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": `package p
+
+// F is straight.
+func F() {}
+
+func G(x int) int {
+	if x > 0 && x < 10 {
+		return x
+	}
+	for i := 0; i < x; i++ {
+	}
+	switch x {
+	case 1:
+	default:
+	}
+	return 0
+}
+`,
+		"b.go": "package p\n\nvar V = 1",
+	})
+	var a, b = pkg.SrcFile("a.go").Complexity(), pkg.SrcFile("b.go").Complexity()
+	if want := (FileComplexity{Functions: 2, TotalCyclomatic: 6, MaxCyclomatic: 5, LOC: 17,
+		CommentLines: 1, CommentRatio: 1.0 / 17}); a != want {
+		t.Errorf("Complexity of a.go = %+v, want %+v", a, want)
+	}
+	if want := (FileComplexity{LOC: 3}); b != want {
+		t.Errorf("Complexity of b.go = %+v, want %+v", b, want)
+	}
+
+	complexity := pkg.Complexity()
+	if len(complexity.Files) != 2 || complexity.Files["a.go"] != a || complexity.Files["b.go"] != b {
+		t.Errorf("Complexity.Files = %+v, want a.go and b.go", complexity.Files)
+	}
+	if want := (FileComplexity{Functions: 2, TotalCyclomatic: 6, MaxCyclomatic: 5, LOC: 20,
+		CommentLines: 1, CommentRatio: 1.0 / 20}); complexity.FileComplexity != want {
+		t.Errorf("Complexity of package = %+v, want %+v", complexity.FileComplexity, want)
+	}
+}
+
+func TestFileComplexityScore(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		complexity FileComplexity
+		score      float64
+	}{
+		{"empty", FileComplexity{}, 80},
+		{"straight", FileComplexity{Functions: 2, TotalCyclomatic: 2, MaxCyclomatic: 1, LOC: 10,
+			CommentLines: 2, CommentRatio: 0.2}, 100},
+		{"half", FileComplexity{Functions: 1, TotalCyclomatic: 8, MaxCyclomatic: 8, LOC: 10,
+			CommentLines: 1, CommentRatio: 0.1}, 0.4*(100-700.0/29) + 0.4*50 + 0.2*50},
+		{"complex", FileComplexity{Functions: 1, TotalCyclomatic: 40, MaxCyclomatic: 40, LOC: 10}, 0},
+	} {
+		if score := test.complexity.Score(); math.Abs(score-test.score) > 1e-9 {
+			t.Errorf("%s: Score = %v, want %v", test.name, score, test.score)
+		}
+	}
+}
+
+func TestProgramMetrics(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\n// T is a type.\ntype T int\n\nfunc F(x int) int {\n\tif x > 0 {\n\t\treturn x\n\t}\n\treturn 0\n}\n",
+		"b/b.go": "package b\n\ntype U struct{}\n\nfunc (U) M() {}\n\nfunc G() {}\n",
+		"c/c.go": "package c\n\nvar C = undefined\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := prog.Metrics()
+	if want := (ProgramMetrics{PackageCount: 3, FileCount: 3, TotalLOC: 21, TotalFunctions: 3, TotalTypes: 2,
+		MeanCyclomaticComplexity: 4.0 / 3, MaxCyclomaticComplexity: 2, TypeRatio: 2.0 / 3,
+		IllTypedPackages: 1}); metrics != want {
+		t.Errorf("Metrics = %+v, want %+v", metrics, want)
+	}
+	var want = "packages                    3\n" +
+		"files                       3\n" +
+		"lines of code               21\n" +
+		"functions                   3\n" +
+		"named types                 2\n" +
+		"mean cyclomatic complexity  1.33\n" +
+		"max cyclomatic complexity   2\n" +
+		"types per function          0.67\n" +
+		"ill-typed packages          1\n"
+	if got := metrics.String(); got != want {
+		t.Errorf("String =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCommentCoverage(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\n// F is documented.\nfunc F() {}\n\nfunc G() {}\n\n// h is unexported.\nfunc h() {}\n",
+		"b.go": "package p\n\ntype T struct{}\n\n// M is documented.\nfunc (T) M() {}\n\n// V is not a function.\nvar V = 1\n",
+	})
+	for _, test := range []struct {
+		file             string
+		commented, total int
+	}{
+		{"a.go", 1, 2},
+		{"b.go", 1, 1},
+	} {
+		if commented, total := pkg.SrcFile(test.file).CommentCoverage(); commented != test.commented || total != test.total {
+			t.Errorf("%s: CommentCoverage = %d, %d, want %d, %d", test.file, commented, total, test.commented, test.total)
+		}
+	}
+	if commented, total := pkg.CommentCoverage(); commented != 2 || total != 3 {
+		t.Errorf("CommentCoverage of package = %d, %d, want 2, 3", commented, total)
+	}
+}
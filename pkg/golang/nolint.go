@@ -0,0 +1,125 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the suppression of Diagnostic by the `//nolint` comments in
+// source files, which silences all findings or those of the listed categories on annotated lines.
+package golang
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// NoLintDirective is the prefix of comments that suppress the diagnostics, written as `//nolint` to
+// suppress all findings or `//nolint:cat1,cat2` (or `//nolint:cat1, cat2`) to suppress those of the
+// listed categories only, optionally followed by the reason as `//nolint:cat1 // the reason`.
+const NoLintDirective = "//nolint"
+
+// parseNoLint parses the comment as a nolint directive, and returns the categories it suppresses
+// (empty for all categories) and whether the comment is a nolint directive.
+func parseNoLint(comment *ast.Comment) ([]string, bool) {
+	if comment == nil || !strings.HasPrefix(comment.Text, NoLintDirective) {
+		return nil, false
+	}
+	var text = comment.Text[len(NoLintDirective):]
+	if len(text) == 0 || text[0] == ' ' || text[0] == '\t' {
+		return nil, true
+	} else if text[0] != ':' {
+		return nil, false // e.g. //nolintfoo
+	}
+	if end := strings.Index(text, "//"); end >= 0 {
+		text = text[:end] // e.g. //nolint:errcheck, unused // the reason
+	}
+	var categories []string
+	for _, category := range strings.Split(text[1:], ",") {
+		var fields = strings.Fields(category)
+		if len(fields) > 0 {
+			categories = append(categories, fields[0])
+		}
+		if len(fields) > 1 {
+			break // e.g. //nolint:errcheck the reason without marker
+		}
+	}
+	return categories, true
+}
+
+// noLintLines returns the map from lines to the categories suppressed on them by the nolint
+// directives, where an empty list suppresses all categories. A directive trailing the code suppresses
+// its own line, and a directive on its own line suppresses the line immediately below it.
+func (file *SrcFile) noLintLines() map[int][]string {
+	var lines = make(map[int][]string)
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return lines
+	}
+	var suppressAll = make(map[int]bool)
+	for _, group := range file.syntax.Comments {
+		for _, comment := range group.List {
+			categories, ok := parseNoLint(comment)
+			if !ok {
+				continue
+			}
+			position := file.pkg.fileSet.Position(comment.Pos())
+			var target = position.Line
+			if file.isLineStart(position.Offset) {
+				target++
+			}
+			if len(categories) == 0 {
+				suppressAll[target] = true
+			}
+			lines[target] = append(lines[target], categories...)
+		}
+	}
+	for line := range suppressAll {
+		lines[line] = []string{}
+	}
+	return lines
+}
+
+// isLineStart checks whether only the spaces precede the offset on its line in the code.
+func (file *SrcFile) isLineStart(offset int) bool {
+	if offset > len(file.code) {
+		return false
+	}
+	var begin = strings.LastIndex(file.code[:offset], NewLine) + 1
+	return len(strings.TrimSpace(file.code[begin:offset])) == 0
+}
+
+// isNoLint checks whether the categories listed by nolint directive suppress the given category.
+func isNoLint(categories []string, category string) bool {
+	return len(categories) == 0 || contains(categories, category)
+}
+
+// IsSuppressed checks whether the diagnostic is positioned in this source file on a line annotated
+// by a nolint directive that matches its category.
+func (file *SrcFile) IsSuppressed(diag Diagnostic) bool {
+	line, _, ok := file.LineColumn(diag.Pos)
+	if !ok {
+		return false
+	}
+	categories, annotated := file.noLintLines()[line]
+	return annotated && isNoLint(categories, diag.Category)
+}
+
+// FilterSuppressed returns the diagnostics that are not suppressed by nolint directives in the
+// source files of this package, and the diagnostics outside the package are kept as they are.
+func (pkg *Package) FilterSuppressed(diagnostics []Diagnostic) []Diagnostic {
+	if pkg == nil || pkg.fileSet == nil {
+		return diagnostics
+	}
+	var fileLines = make(map[string]map[int][]string)
+	var remains = make([]Diagnostic, 0, len(diagnostics))
+	for _, diag := range diagnostics {
+		var position = pkg.fileSet.Position(diag.Pos)
+		if file, ok := pkg.srcFiles[position.Filename]; ok && position.IsValid() {
+			if _, ok := fileLines[file.path]; !ok {
+				fileLines[file.path] = file.noLintLines()
+			}
+			categories, annotated := fileLines[file.path][position.Line]
+			if annotated && isNoLint(categories, diag.Category) {
+				continue
+			}
+		}
+		remains = append(remains, diag)
+	}
+	return remains
+}
@@ -0,0 +1,83 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestParseNoLint(t *testing.T) {
+	for _, test := range []struct {
+		text       string
+		categories []string
+		ok         bool
+	}{
+		{"//nolint", nil, true},
+		{"//nolint // the reason", nil, true},
+		{"//nolint:errcheck", []string{"errcheck"}, true},
+		{"//nolint:a,b", []string{"a", "b"}, true},
+		{"//nolint:a, b", []string{"a", "b"}, true},
+		{"//nolint:a, b // the reason", []string{"a", "b"}, true},
+		{"//nolint:a,b//the reason", []string{"a", "b"}, true},
+		{"//nolint:a the reason, without marker", []string{"a"}, true},
+		{"//nolintfoo", nil, false},
+		{"// nolint", nil, false},
+		{"// a comment", nil, false},
+	} {
+		categories, ok := parseNoLint(&ast.Comment{Text: test.text})
+		if ok != test.ok || !reflect.DeepEqual(categories, test.categories) {
+			t.Errorf("parseNoLint(%q) = %q, %v, want %q, %v", test.text, categories, ok,
+				test.categories, test.ok)
+		}
+	}
+}
+
+func TestFilterSuppressed(t *testing.T) {
+	const code = `package p
+
+func f() {
+	g() //nolint:errcheck, unused // g never fails
+	g() //nolint:unused
+	//nolint
+	g()
+	g()
+}
+
+func g() error { return nil }
+`
+	pkg, err := NewVirtualPackage("p", "p", map[string]string{"p.go": code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var srcFile = pkg.SrcFile("p.go")
+	var diagOf = func(line int, category string) Diagnostic {
+		var pos = pkg.FileSet().File(srcFile.Syntax().Pos()).LineStart(line) + 1
+		return Diagnostic{Pos: pos, Category: category, Message: category}
+	}
+	var diagnostics = []Diagnostic{
+		diagOf(4, "errcheck"), // suppressed by the list
+		diagOf(4, "unused"),   // suppressed by the list after a space
+		diagOf(4, "other"),    // not listed
+		diagOf(5, "errcheck"), // not listed
+		diagOf(6, "errcheck"), // the directive on its own line doesn't cover itself
+		diagOf(7, "errcheck"), // suppressed by the directive above
+		diagOf(8, "errcheck"), // not annotated
+		{Pos: token.NoPos, Message: "unpositioned"},
+	}
+	var remains []string
+	for _, diag := range pkg.FilterSuppressed(diagnostics) {
+		line, _, _ := srcFile.LineColumn(diag.Pos)
+		remains = append(remains, fmt.Sprintf("%s@%d", diag.Message, line))
+	}
+	var want = []string{"other@4", "errcheck@5", "errcheck@6", "errcheck@8", "unpositioned@0"}
+	if !reflect.DeepEqual(remains, want) {
+		t.Errorf("FilterSuppressed() = %v, want %v", remains, want)
+	}
+	for _, diag := range diagnostics[:2] {
+		if !srcFile.IsSuppressed(diag) {
+			t.Errorf("IsSuppressed(%s) = false, want true", diag.Message)
+		}
+	}
+}
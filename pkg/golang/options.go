@@ -0,0 +1,193 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file defines LoadOptions, which configures the target platform and the other
+// settings used by the loaders to parse and type-check the source files of packages.
+package golang
+
+import (
+	"go/build"
+	"go/types"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LoadOptions configures how the loaders parse and type-check the source files and packages. The
+// nil LoadOptions is valid and loads the code as it would compile on the current platform.
+type LoadOptions struct {
+	GOOS   string // GOOS is the target operating system, or build.Default.GOOS if it is empty
+	GOARCH string // GOARCH is the target architecture, or build.Default.GOARCH if it is empty
+
+	// BuildTags are the additional build tags satisfied when evaluating the build constraints.
+	BuildTags []string
+
+	// Overlay maps from the absolute paths of source files to their contents, which are parsed in
+	// place of the files on disk, e.g. to analyze the unsaved buffers in editors.
+	Overlay map[string][]byte
+
+	// MinimalTypeInfo populates only Types, Defs and Uses of the types.Info in type checking, which
+	// reduces the peak memory of loading huge repositories. The queries relying on other maps (e.g.
+	// the Scopes in ClosureCaptures) find nothing, and SSA can't be built for such packages.
+	MinimalTypeInfo bool
+
+	// IncludeVendor, IncludeTestdata and IncludeHidden opt back in walking the `vendor`, `testdata`
+	// and hidden (dot) directories when loading the packages under a root directory, which are all
+	// skipped by default. The vendored packages are still loaded for imports even if skipped.
+	IncludeVendor   bool
+	IncludeTestdata bool
+	IncludeHidden   bool
+
+	// Importer resolves the imports that are neither in the module nor vendored, in place of the
+	// default importer of GOROOT types, or nil to use the default one.
+	Importer types.Importer
+
+	// ExcludeTests skips the test files (`*_test.go`) in parsing the directories, and hence the test
+	// functions in packages and the external test packages, which are all loaded by default.
+	ExcludeTests bool
+
+	// Concurrency is the number of goroutines parsing the directories when loading the packages
+	// under a root directory, or GOMAXPROCS if it is not positive.
+	Concurrency int
+
+	// OnEvent is called with the progress events in loading the packages under a root directory,
+	// which is invoked serially from the goroutine calling the loader, or nil to ignore events.
+	OnEvent func(event LoadEvent)
+}
+
+// LoadEventKind is the kind of progress event in loading the packages under a root directory.
+type LoadEventKind string
+
+const (
+	LoadEventParsingDir      LoadEventKind = "parsing dir"       // a directory is going to be parsed
+	LoadEventTypeCheckFailed LoadEventKind = "type-check failed" // a package loaded is ill-typed
+	LoadEventPackageLoaded   LoadEventKind = "package loaded"    // a directory's packages are loaded
+	LoadEventDirSkipped      LoadEventKind = "dir skipped"       // a directory fails to be loaded
+)
+
+// LoadEvent is a progress event in loading the packages under a root directory, where each directory
+// is parsed and then loaded (or skipped) in order, and Count of Total directories are processed.
+type LoadEvent struct {
+	Kind    LoadEventKind // Kind is the kind of event
+	DirPath string        // DirPath is the absolute path of the directory of event
+	PkgPath string        // PkgPath is the path of the package loaded, or empty if none
+	Count   int           // Count is the number of directories parsed or loaded including this one
+	Total   int           // Total is the number of directories to be parsed or loaded
+	Err     error         // Err is the cause of skipped directory or ill-typed package
+}
+
+// emit calls the OnEvent callback in options with the event if any.
+func (opts *LoadOptions) emit(event LoadEvent) {
+	if opts != nil && opts.OnEvent != nil {
+		opts.OnEvent(event)
+	}
+}
+
+// TestdataDirName is the name of directory ignored by go tool, which holds the data used by tests.
+const TestdataDirName = "testdata"
+
+// SkipDir checks whether the directory of name should be skipped when walking the packages under
+// a root directory, i.e. the vendor, testdata and hidden directories unless they are opted in.
+func (opts *LoadOptions) SkipDir(dirName string) bool {
+	switch {
+	case dirName == VendorDirName:
+		return opts == nil || !opts.IncludeVendor
+	case dirName == TestdataDirName:
+		return opts == nil || !opts.IncludeTestdata
+	case len(dirName) > 1 && strings.HasPrefix(dirName, "."):
+		return opts == nil || !opts.IncludeHidden
+	}
+	return false
+}
+
+// excludesTests checks whether the test files and external test packages are excluded by options.
+func (opts *LoadOptions) excludesTests() bool {
+	return opts != nil && opts.ExcludeTests
+}
+
+// excludesFile checks whether the source file of name is a test file excluded by options.
+func (opts *LoadOptions) excludesFile(fileName string) bool {
+	return opts.excludesTests() && strings.HasSuffix(fileName, "_test"+GoFileSuffix)
+}
+
+// concurrency returns the number of goroutines parsing the directories
+func (opts *LoadOptions) concurrency() int {
+	if opts != nil && opts.Concurrency > 0 {
+		return opts.Concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// goos returns the target operating system for build constraints
+func (opts *LoadOptions) goos() string {
+	if opts != nil && len(opts.GOOS) > 0 {
+		return opts.GOOS
+	}
+	return build.Default.GOOS
+}
+
+// goarch returns the target architecture for build constraints and type sizes
+func (opts *LoadOptions) goarch() string {
+	if opts != nil && len(opts.GOARCH) > 0 {
+		return opts.GOARCH
+	}
+	return build.Default.GOARCH
+}
+
+// overlayOf returns the content of the source file in overlay, or false if it isn't overlaid
+func (opts *LoadOptions) overlayOf(path string) ([]byte, bool) {
+	if opts == nil || len(opts.Overlay) == 0 {
+		return nil, false
+	}
+	content, ok := opts.Overlay[filepath.Clean(path)]
+	return content, ok
+}
+
+// Option configures the LoadOptions used by Load, e.g. Load(dir, WithGOOS("linux"), WithTests(false)).
+type Option func(opts *LoadOptions)
+
+// WithImporter sets the importer resolving the imports out of module and vendor.
+func WithImporter(importer types.Importer) Option {
+	return func(opts *LoadOptions) { opts.Importer = importer }
+}
+
+// WithGOOS sets the target operating system.
+func WithGOOS(goos string) Option {
+	return func(opts *LoadOptions) { opts.GOOS = goos }
+}
+
+// WithGOARCH sets the target architecture.
+func WithGOARCH(goarch string) Option {
+	return func(opts *LoadOptions) { opts.GOARCH = goarch }
+}
+
+// WithBuildTags adds the build tags satisfied when evaluating build constraints.
+func WithBuildTags(tags ...string) Option {
+	return func(opts *LoadOptions) { opts.BuildTags = append(opts.BuildTags, tags...) }
+}
+
+// WithTests sets whether the test files and external test packages are loaded.
+func WithTests(withTests bool) Option {
+	return func(opts *LoadOptions) { opts.ExcludeTests = !withTests }
+}
+
+// WithOverlay sets the contents of source files parsed in place of those on disk.
+func WithOverlay(overlay map[string][]byte) Option {
+	return func(opts *LoadOptions) { opts.Overlay = overlay }
+}
+
+// WithConcurrency sets the number of goroutines parsing the directories.
+func WithConcurrency(concurrency int) Option {
+	return func(opts *LoadOptions) { opts.Concurrency = concurrency }
+}
+
+// newLoadOptions applies the options in order to an empty LoadOptions.
+func newLoadOptions(options ...Option) *LoadOptions {
+	var opts = &LoadOptions{}
+	for _, option := range options {
+		if option != nil {
+			option(opts)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,150 @@
+package golang
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeImporter provides the packages it holds and imports the others from GOROOT.
+type fakeImporter map[string]*types.Package
+
+func (imp fakeImporter) Import(path string) (*types.Package, error) {
+	if typePkg, ok := imp[path]; ok {
+		return typePkg, nil
+	}
+	return importer.Default().Import(path)
+}
+
+func TestLoadWithTests(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"sub/a.go":      "package sub\n\nfunc A() {}\n",
+		"sub/a_test.go": "package sub\n\nfunc helperInTest() {}\n",
+		"sub/x_test.go": "package sub_test\n\nimport \"example.com/m/sub\"\n\nvar _ = sub.A\n",
+	})
+	for _, withTests := range []bool{true, false} {
+		prog, err := Load(rootDir, WithTests(withTests))
+		if err != nil {
+			t.Fatalf("WithTests(%v): %v", withTests, err)
+		}
+		pkg := mustPackage(t, prog, testModulePath+"/sub")
+		var wantFiles = []string{"a.go"}
+		if withTests {
+			wantFiles = []string{"a.go", "a_test.go"}
+		}
+		if gotFiles := baseNamesOf(pkg.GoFiles()); !reflect.DeepEqual(gotFiles, wantFiles) {
+			t.Errorf("WithTests(%v): GoFiles = %v, want %v", withTests, gotFiles, wantFiles)
+		}
+		if got := pkg.Lookup("helperInTest") != nil; got != withTests {
+			t.Errorf("WithTests(%v): helperInTest declared = %v", withTests, got)
+		}
+		if got := prog.Package(testModulePath+"/sub_test") != nil; got != withTests {
+			t.Errorf("WithTests(%v): external test package loaded = %v", withTests, got)
+		}
+	}
+}
+
+func TestLoadOptionsReachTypeConfig(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go": "package p\n\nimport \"example.com/ext\"\n\nvar V int = ext.N\n",
+	})
+	var extPkg = types.NewPackage("example.com/ext", "ext")
+	extPkg.Scope().Insert(types.NewVar(token.NoPos, extPkg, "N", types.Typ[types.Int]))
+	extPkg.MarkComplete()
+
+	for _, test := range []struct {
+		goarch  string
+		intSize int64
+	}{
+		{"amd64", 8},
+		{"386", 4},
+		{"arm", 4},
+	} {
+		prog, err := Load(rootDir, WithGOOS("linux"), WithGOARCH(test.goarch),
+			WithImporter(fakeImporter{extPkg.Path(): extPkg}))
+		if err != nil {
+			t.Fatalf("GOARCH=%s: %v", test.goarch, err)
+		}
+		pkg := mustPackage(t, prog, testModulePath+"/p")
+		if pkg.LoadInfo().HasErrors() {
+			t.Fatalf("GOARCH=%s: unexpected errors: %v", test.goarch, pkg.LoadInfo().AllErrors())
+		}
+		if got := (*pkg.TypeSize()).Sizeof(types.Typ[types.Int]); got != test.intSize {
+			t.Errorf("GOARCH=%s: sizeof(int) = %d, want %d", test.goarch, got, test.intSize)
+		}
+		var imported = pkg.TypePkg().Imports()
+		if len(imported) != 1 || imported[0] != extPkg {
+			t.Errorf("GOARCH=%s: imports = %v, want the package of importer", test.goarch, imported)
+		}
+	}
+}
+
+func TestLoadEvents(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\nfunc A() {}\n",
+		"a/a_test.go": "package a_test\n",
+		"b/b.go":      "package b\n\nfunc B() { undefined() }\n",
+		"c/c.go":      "package c\n\nfunc C( {}\n",
+	})
+	var events = make(map[LoadEventKind][]string)
+	_, err := Load(rootDir, func(opts *LoadOptions) {
+		opts.OnEvent = func(event LoadEvent) {
+			relPath, _ := filepath.Rel(rootDir, event.DirPath)
+			events[event.Kind] = append(events[event.Kind], filepath.ToSlash(relPath))
+			if event.Total != 3 || event.Count < 1 || event.Count > event.Total {
+				t.Errorf("%s %s: count %d of %d", event.Kind, relPath, event.Count, event.Total)
+			}
+		}
+	})
+	if err == nil {
+		t.Errorf("Load() = nil, want the error of skipped directory")
+	}
+	for kind, want := range map[LoadEventKind][]string{
+		LoadEventParsingDir:      {"a", "b", "c"},
+		LoadEventPackageLoaded:   {"a", "b"},
+		LoadEventTypeCheckFailed: {"b"},
+		LoadEventDirSkipped:      {"c"},
+	} {
+		if got := events[kind]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s events in = %v, want %v", kind, got, want)
+		}
+	}
+}
+
+func TestLoadMinimalTypeInfo(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/p.go": "package p\n\ntype T struct{ N int }\n\nvar V = T{}.N\n",
+	})
+	for _, minimal := range []bool{true, false} {
+		pkgs, err := loadAllDirectoriesByFree(rootDir, &LoadOptions{MinimalTypeInfo: minimal})
+		if err != nil || len(pkgs) != 1 {
+			t.Fatalf("MinimalTypeInfo=%v: loading = %v, %v", minimal, pkgs, err)
+		}
+		var pkg, selector = pkgs[0], (*ast.SelectorExpr)(nil)
+		for _, path := range pkg.GoFiles() {
+			ast.Inspect(pkg.SrcFile(path).Syntax(), func(node ast.Node) bool {
+				if expr, ok := node.(*ast.SelectorExpr); ok {
+					selector = expr
+				}
+				return true
+			})
+		}
+		if selector == nil {
+			t.Fatalf("MinimalTypeInfo=%v: no selector expression is found", minimal)
+		}
+		if typ := pkg.TypeOf(selector); typ != types.Typ[types.Int] {
+			t.Errorf("MinimalTypeInfo=%v: TypeOf(T{}.N) = %v, want int", minimal, typ)
+		}
+		if obj := pkg.TypeInfo().Uses[selector.Sel]; obj == nil || obj.Name() != "N" {
+			t.Errorf("MinimalTypeInfo=%v: Uses[N] = %v, want the field N", minimal, obj)
+		}
+		var info = pkg.TypeInfo()
+		if got := len(info.Selections) == 0 && len(info.Scopes) == 0; got != minimal {
+			t.Errorf("MinimalTypeInfo=%v: Selections and Scopes are empty = %v", minimal, got)
+		}
+	}
+}
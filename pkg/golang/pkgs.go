@@ -8,9 +8,17 @@
 package golang
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
 	"go/token"
 	"go/types"
+	"path/filepath"
+	"sort"
 	"time"
+
+	"golang.org/x/tools/go/ssa"
 )
 
 // Package represents a package with its source files (modeled as SrcFile) being loaded from code.
@@ -31,6 +39,12 @@ type Package struct {
 	typePkg *types.Package // typePkg declares the package
 	typInfo *types.Info    // typInfo records the types and declarations of any variable and expression
 	typSize *types.Sizes   // typSize records the size of bytes hold by any type in this package
+	ssaPkg  *ssa.Package   // ssaPkg is the static single assignment form of package, or nil
+
+	importer types.Importer           // importer resolves the imported packages in type checking
+	ssaFuncs map[string]*ssa.Function // ssaFuncs cache the SSA functions found by their names
+	testPkg  *Package                 // testPkg is the external test package (`_test`), or nil
+	options  *LoadOptions             // options are used to type-check this package, or nil
 }
 
 // LoadInfo records the information of the last loading a package, including the syntactic, types
@@ -43,6 +57,42 @@ type LoadInfo struct {
 	FileErrors   []error   // FileErrors are a set of errors when parsing the file
 	TypeErrors   []error   // TypeErrors are a set of errors in checking the types
 	DepsErrors   []error   // DepsErrors are a set of errors in dependency imports
+	IsCached     bool      // IsCached is true if only metadata is restored from cache
+
+	FileLoadTimes map[string]time.Duration // FileLoadTimes map from files to durations of reading and parsing
+}
+
+// HasErrors checks whether any error occurs in loading, including the ill-typed package of which
+// type errors are not recorded.
+func (info *LoadInfo) HasErrors() bool {
+	return info != nil && (info.IllTyped || len(info.AllErrors()) > 0)
+}
+
+// AllErrors returns the errors in loading, i.e. the FileErrors, TypeErrors and DepsErrors in order.
+func (info *LoadInfo) AllErrors() []error {
+	if info == nil {
+		return nil
+	}
+	var errs = make([]error, 0, len(info.FileErrors)+len(info.TypeErrors)+len(info.DepsErrors))
+	errs = append(errs, info.FileErrors...)
+	errs = append(errs, info.TypeErrors...)
+	return append(errs, info.DepsErrors...)
+}
+
+// SlowestFile returns the path of source file that takes the longest time to read and parse, along
+// with its duration, or empty if no file load time is recorded.
+func (info *LoadInfo) SlowestFile() (string, time.Duration) {
+	var slowestPath string
+	var slowestTime time.Duration
+	if info != nil {
+		for path, duration := range info.FileLoadTimes {
+			if len(slowestPath) == 0 || duration > slowestTime ||
+				(duration == slowestTime && path < slowestPath) {
+				slowestPath, slowestTime = path, duration
+			}
+		}
+	}
+	return slowestPath, slowestTime
 }
 
 // newPackage creates a new package in the program given its name, logical path and directory path.
@@ -59,6 +109,11 @@ func newPackage(program *Program, pkgName, pkgPath, dirPath string) *Package {
 		typePkg:  nil,
 		typInfo:  nil,
 		typSize:  nil,
+		ssaPkg:   nil,
+		importer: nil,
+		ssaFuncs: nil,
+		testPkg:  nil,
+		options:  nil,
 	}
 }
 
@@ -103,6 +158,21 @@ func (pkg *Package) IsLoaded() bool {
 	return false
 }
 
+// isCached checks whether only the metadata of this package is restored from cache, such that its
+// source files are required to be re-parsed before the syntax and types are available.
+func (pkg *Package) isCached() bool {
+	return pkg.IsLoaded() && pkg.loadInfo.IsCached
+}
+
+// TestPackage is the external test package declared as `package xxx_test` by the test files in the
+// directory of this package, or nil if there is none or it is not loaded.
+func (pkg *Package) TestPackage() *Package {
+	if pkg != nil {
+		return pkg.testPkg
+	}
+	return nil
+}
+
 // LoadInfo records the information of the latest loading for this package
 func (pkg *Package) LoadInfo() *LoadInfo {
 	if pkg != nil {
@@ -125,6 +195,22 @@ func (pkg *Package) GoFiles() []string {
 	return nil
 }
 
+// Hash is the hex-encoded SHA-256 combining the names and hashes of source files in this package
+// (sorted by paths), which changes once any file is added, removed or changed in this package. The
+// names of files rather than absolute paths are hashed, such that copies of a package are equal.
+func (pkg *Package) Hash() string {
+	if pkg == nil {
+		return ""
+	}
+	var paths = pkg.GoFiles()
+	sort.Strings(paths)
+	var hash = sha256.New()
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(hash, "%s\x00%s\n", filepath.Base(path), pkg.srcFiles[path].Hash())
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 // SrcFile returns the source file w.r.t. the absolute file in this package
 func (pkg *Package) SrcFile(path string) *SrcFile {
 	if pkg != nil {
@@ -149,6 +235,60 @@ func (pkg *Package) Imports() []string {
 	return nil
 }
 
+// Reload reads all source files of this package from disk again (by SrcFile.Reload) and type-checks
+// them with the options this package was loaded with, which updates this Package in place such that
+// the references to it remain valid. The SSA form is cleared, which could be built again.
+//
+// The reloading is atomic: if any file fails to be read or parsed, the files and package are restored
+// to the previous state (including the type information and SSA form), and the error is returned.
+// The type errors don't fail the reloading, which are recorded in LoadInfo as the package loaded is
+// ill-typed. Note that the packages importing this one are not checked again, which still refer to
+// the old types.
+func (pkg *Package) Reload() error {
+	// 1. reload the source files and keep their states
+	if pkg == nil {
+		return fmt.Errorf("nil package is used")
+	}
+	var paths = pkg.GoFiles()
+	sort.Strings(paths)
+	var previousPkg = *pkg
+	var previousFiles = make(map[string]SrcFile, len(paths))
+	var restore = func() {
+		*pkg = previousPkg
+		for path, previousFile := range previousFiles {
+			*pkg.srcFiles[path] = previousFile
+		}
+	}
+	var astPkg = &ast.Package{Name: pkg.pkgName, Files: make(map[string]*ast.File)}
+	var dir = &parsedDir{
+		astPkgs: map[string]*ast.Package{pkg.pkgName: astPkg},
+		elapsed: make(map[string]time.Duration),
+		sources: make(map[string][]byte),
+	}
+	for _, path := range paths {
+		var srcFile = pkg.srcFiles[path]
+		previousFiles[path] = *srcFile
+		begTime := time.Now()
+		if err := srcFile.Reload(); err != nil {
+			restore()
+			return err
+		}
+		astPkg.Files[path] = srcFile.syntax
+		dir.elapsed[path] = time.Since(begTime)
+		dir.sources[path] = []byte(srcFile.code)
+	}
+
+	// 2. type-check the files with the previous options
+	pkg.typePkg, pkg.typInfo, pkg.typSize, pkg.imports = nil, nil, nil, nil
+	pkg.importer = nil
+	pkg.ssaPkg, pkg.ssaFuncs = nil, nil
+	if err := parseGoPackageByFree(pkg, dir, previousPkg.options); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
 // TypePkg declares the package and its types
 func (pkg *Package) TypePkg() *types.Package {
 	if pkg != nil {
@@ -183,3 +323,22 @@ func (pkg *Package) newSrcFile(srcPath string) *SrcFile {
 	}
 	return nil
 }
+
+// syntaxFiles returns the source files in this package with syntax tree, sorted by their paths.
+func (pkg *Package) syntaxFiles() []*SrcFile {
+	if pkg == nil {
+		return nil
+	}
+	var paths []string
+	for path, file := range pkg.srcFiles {
+		if file != nil && file.syntax != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	var files = make([]*SrcFile, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, pkg.srcFiles[path])
+	}
+	return files
+}
@@ -0,0 +1,163 @@
+package golang
+
+import (
+	"errors"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// reloadFixture loads the package p of a module written with the code of p/p.go on GOARCH=386, and
+// returns it along with the path of p/p.go.
+func reloadFixture(t *testing.T, code string) (*Package, string) {
+	t.Helper()
+	rootDir := writeModule(t, map[string]string{"p/p.go": code})
+	prog, err := Load(rootDir, WithGOARCH("386"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mustPackage(t, prog, testModulePath+"/p"), filepath.Join(rootDir, "p", "p.go")
+}
+
+// rewrite overwrites the file with the code.
+func rewrite(t *testing.T, path, code string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackageReload(t *testing.T) {
+	pkg, path := reloadFixture(t, "package p\n\nfunc A() {}\n")
+	if err := pkg.BuildSSA(); err != nil {
+		t.Fatal(err)
+	}
+	rewrite(t, path, "package p\n\nfunc A() {}\n\nfunc B() int { return 1 }\n")
+	if err := pkg.Reload(); err != nil {
+		t.Fatalf("Reload() = %v", err)
+	}
+	if pkg.Lookup("B") == nil {
+		t.Errorf("B is not declared after Reload")
+	}
+	if pkg.SrcFile(path).Syntax() == nil || len(pkg.SrcFile(path).Lines()) != 5 {
+		t.Errorf("file is not reloaded: %q", pkg.SrcFile(path).Code())
+	}
+	if pkg.SSAPkg() != nil {
+		t.Errorf("SSA form is not cleared after Reload")
+	}
+	if got := (*pkg.TypeSize()).Sizeof(types.Typ[types.Int]); got != 4 {
+		t.Errorf("sizeof(int) = %d after Reload, want 4 of GOARCH=386 in options", got)
+	}
+}
+
+func TestPackageReloadRestores(t *testing.T) {
+	const code = "package p\n\nfunc A() {}\n"
+	pkg, path := reloadFixture(t, code)
+	if err := pkg.BuildSSA(); err != nil {
+		t.Fatal(err)
+	}
+	var typePkg, ssaPkg, loadInfo = pkg.TypePkg(), pkg.SSAPkg(), pkg.LoadInfo()
+	rewrite(t, path, "package p\n\nfunc A( {}\n")
+	if err := pkg.Reload(); err == nil {
+		t.Errorf("Reload() = nil, want the syntax error")
+	}
+	if pkg.TypePkg() != typePkg || pkg.SSAPkg() != ssaPkg || pkg.LoadInfo() != loadInfo {
+		t.Errorf("types, SSA or load info are not restored")
+	}
+	var srcFile = pkg.SrcFile(path)
+	if srcFile.Code() != code || len(srcFile.Members()) == 0 {
+		t.Errorf("file is not restored: %q with %d members", srcFile.Code(), len(srcFile.Members()))
+	}
+}
+
+func TestPackageReloadIllTyped(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		code string
+	}{
+		{"ill-typed before", "package p\n\nfunc A() { undefined() }\n"},
+		{"well-typed before", "package p\n\nfunc A() {}\n"},
+	} {
+		pkg, path := reloadFixture(t, test.code)
+		rewrite(t, path, "package p\n\nfunc A() { undefined() }\n\nfunc B() {}\n")
+		if err := pkg.Reload(); err != nil {
+			t.Fatalf("%s: Reload() = %v, want nil for type errors", test.name, err)
+		}
+		var info = pkg.LoadInfo()
+		if !info.IllTyped || len(info.TypeErrors) == 0 || pkg.Lookup("B") == nil {
+			t.Errorf("%s: ill-typed package is not reloaded: %+v", test.name, info)
+		}
+		if code := pkg.SrcFile(path).Code(); code != "package p\n\nfunc A() { undefined() }\n\nfunc B() {}\n" {
+			t.Errorf("%s: file is restored: %q", test.name, code)
+		}
+	}
+}
+
+func TestLoadInfoFileLoadTimes(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/a.go": "package p\n",
+		"p/b.go": "package p\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := mustPackage(t, prog, testModulePath+"/p").LoadInfo()
+	var aPath, bPath = filepath.Join(rootDir, "p", "a.go"), filepath.Join(rootDir, "p", "b.go")
+	if len(info.FileLoadTimes) != 2 {
+		t.Fatalf("FileLoadTimes = %v, want the durations of a.go and b.go", info.FileLoadTimes)
+	}
+	for _, path := range []string{aPath, bPath} {
+		if duration, ok := info.FileLoadTimes[path]; !ok || duration < 0 {
+			t.Errorf("FileLoadTimes[%s] = %v, %v, want the duration", path, duration, ok)
+		}
+	}
+
+	for _, test := range []struct {
+		times    map[string]time.Duration
+		path     string
+		duration time.Duration
+	}{
+		{nil, "", 0},
+		{map[string]time.Duration{aPath: 3, bPath: 5}, bPath, 5},
+		{map[string]time.Duration{aPath: 5, bPath: 5}, aPath, 5},
+	} {
+		info := &LoadInfo{FileLoadTimes: test.times}
+		if path, duration := info.SlowestFile(); path != test.path || duration != test.duration {
+			t.Errorf("SlowestFile(%v) = %s, %v, want %s, %v", test.times, path, duration, test.path, test.duration)
+		}
+	}
+}
+
+func TestLoadInfoHasErrors(t *testing.T) {
+	pkg, _ := reloadFixture(t, "package p\n\nvar A int = \"a\"\n")
+	info := pkg.LoadInfo()
+	if len(info.FileErrors) != 0 || len(info.DepsErrors) != 0 || len(info.TypeErrors) != 1 {
+		t.Fatalf("errors = %v, want only the type error", info.AllErrors())
+	}
+	if !info.HasErrors() || len(info.AllErrors()) != 1 {
+		t.Errorf("HasErrors = %v with %d errors, want true with 1", info.HasErrors(), len(info.AllErrors()))
+	}
+
+	var fileErr, typeErr, depsErr = errors.New("file"), errors.New("type"), errors.New("deps")
+	info = &LoadInfo{FileErrors: []error{fileErr}, TypeErrors: []error{typeErr}, DepsErrors: []error{depsErr}}
+	if got := info.AllErrors(); !reflect.DeepEqual(got, []error{fileErr, typeErr, depsErr}) {
+		t.Errorf("AllErrors = %v, want file, type and deps errors in order", got)
+	}
+	for _, test := range []struct {
+		info      *LoadInfo
+		hasErrors bool
+	}{
+		{nil, false},
+		{&LoadInfo{}, false},
+		{&LoadInfo{IllTyped: true}, true},
+		{&LoadInfo{DepsErrors: []error{depsErr}}, true},
+	} {
+		if got := test.info.HasErrors(); got != test.hasErrors {
+			t.Errorf("HasErrors of %+v = %v, want %v", test.info, got, test.hasErrors)
+		}
+	}
+}
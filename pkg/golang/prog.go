@@ -7,9 +7,13 @@ package golang
 
 import (
 	"fmt"
+	"go/token"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"golang.org/x/tools/go/ssa"
 )
 
 // Module gives the information in `go.mod` file that defines the module of project be analyzed.
@@ -37,7 +41,7 @@ func newModule(goModFile string) (*Module, error) {
 	if err != nil {
 		return nil, err
 	} else if len(bytes) == 0 {
-		return nil, fmt.Errorf("empty file: %s", goModFile)
+		return nil, fmt.Errorf("%w: %s", ErrEmptyFile, goModFile)
 	}
 	lines := strings.Split(string(bytes), NewLine)
 	module := &Module{
@@ -51,17 +55,17 @@ func newModule(goModFile string) (*Module, error) {
 
 	// 3. construct the go.mod lines in the Module
 	for _, line := range lines {
+		line, comment := splitGoModComment(line)
 		if strings.HasPrefix(line, ModulePrefix) {
 			module.ModuleName = strings.TrimSpace(line[len(ModulePrefix):])
 		} else if strings.HasPrefix(line, VersionPrefix) {
 			module.GoVersion = strings.TrimSpace(line[len(VersionPrefix):])
 		} else if strings.HasPrefix(line, TabString) {
-			items := strings.Split(strings.TrimSpace(line), SpaceChar)
+			items := strings.Fields(line)
 			if len(items) >= 2 {
 				depPkgPath := strings.TrimSpace(items[0])
 				depVersion := strings.TrimSpace(items[1])
-				lastItem := strings.TrimSpace(items[len(items)-1])
-				if lastItem == GoModIndirect {
+				if isIndirectComment(comment) {
 					module.IndirectDeps[depPkgPath] = depVersion
 				} else {
 					module.DirectDeps[depPkgPath] = depVersion
@@ -72,10 +76,121 @@ func newModule(goModFile string) (*Module, error) {
 	return module, nil
 }
 
+// splitGoModComment splits the line in go.mod into the code before `//` (with trailing spaces
+// trimmed) and the text of its inline comment, such that a commented-out line has empty code.
+//
+// The go.mod grammar has only `//` comments, so `/*` is kept as text (e.g. in `// see /* here`).
+func splitGoModComment(line string) (code, comment string) {
+	if index := strings.Index(line, "//"); index >= 0 {
+		return strings.TrimRight(line[:index], " \t\r"), strings.TrimSpace(line[index+2:])
+	}
+	return strings.TrimRight(line, " \t\r"), ""
+}
+
+// isIndirectComment checks whether the inline comment of requirement marks it as indirect, i.e.
+// `// indirect` or `// indirect; other comments`.
+func isIndirectComment(comment string) bool {
+	return comment == GoModIndirect || strings.HasPrefix(comment, GoModIndirect+";")
+}
+
+// dirOf returns the absolute path of directory of the package in this module with the import path,
+// or false if the package is not in this module.
+func (module *Module) dirOf(pkgPath string) (string, bool) {
+	if module == nil || len(module.ModuleName) == 0 {
+		return "", false
+	}
+	if pkgPath == module.ModuleName {
+		return module.RootPath, true
+	}
+	if !strings.HasPrefix(pkgPath, module.ModuleName+"/") {
+		return "", false
+	}
+	relPath := strings.TrimPrefix(pkgPath, module.ModuleName+"/")
+	return filepath.Join(module.RootPath, filepath.FromSlash(relPath)), true
+}
+
+// VerifyError is an inconsistency between the requirements in go.mod and the hashes in go.sum.
+type VerifyError struct {
+	Module  string // Module is the path of the dependency module
+	Version string // Version is the version of the dependency module
+	Missing bool   // Missing is true if go.sum lacks the required one, or false for the extra one
+}
+
+// Error describes the inconsistency of the module version in go.sum.
+func (verifyErr VerifyError) Error() string {
+	if verifyErr.Missing {
+		return fmt.Sprintf("missing in %s: %s %s", GoSumFileName, verifyErr.Module, verifyErr.Version)
+	}
+	return fmt.Sprintf("extra in %s: %s %s", GoSumFileName, verifyErr.Module, verifyErr.Version)
+}
+
+// goSumHashesOf parses the `<module> <version>/go.mod <hash>` lines in go.sum to map from module
+// versions as `<module> <version>` to their hashes, where the hashes of zips are ignored.
+func goSumHashesOf(goSumFile string) (map[string]string, error) {
+	var bytes, err = os.ReadFile(goSumFile)
+	if err != nil {
+		return nil, err
+	}
+	var hashes = make(map[string]string)
+	for _, line := range strings.Split(string(bytes), NewLine) {
+		items := strings.Fields(line)
+		if len(items) == 3 && strings.HasSuffix(items[1], "/"+GoModFileName) {
+			version := strings.TrimSuffix(items[1], "/"+GoModFileName)
+			hashes[items[0]+SpaceChar+version] = items[2]
+		}
+	}
+	return hashes, nil
+}
+
+// VerifyDeps checks that the go.sum in root of this module is consistent with the requirements in
+// go.mod, which returns those required in DirectDeps or IndirectDeps without the go.mod hash in the
+// go.sum as missing, and those in go.sum but not required as extra. Note that go.sum also lists the
+// modules in the build graph that are not required directly, which are reported as extra as well.
+//
+// It doesn't download modules to check the hashes, and a missing go.sum is taken as empty.
+func (module *Module) VerifyDeps() []VerifyError {
+	if module == nil {
+		return nil
+	}
+	hashes, err := goSumHashesOf(filepath.Join(module.RootPath, GoSumFileName))
+	if err != nil {
+		hashes = make(map[string]string)
+	}
+	var required = make(map[string]bool)
+	var verifyErrs []VerifyError
+	for _, deps := range []map[string]string{module.DirectDeps, module.IndirectDeps} {
+		for depPath, depVersion := range deps {
+			required[depPath+SpaceChar+depVersion] = true
+			if _, ok := hashes[depPath+SpaceChar+depVersion]; !ok {
+				verifyErrs = append(verifyErrs, VerifyError{Module: depPath, Version: depVersion, Missing: true})
+			}
+		}
+	}
+	for key := range hashes {
+		if !required[key] {
+			items := strings.SplitN(key, SpaceChar, 2)
+			verifyErrs = append(verifyErrs, VerifyError{Module: items[0], Version: items[1]})
+		}
+	}
+	sort.Slice(verifyErrs, func(i, j int) bool {
+		if verifyErrs[i].Module != verifyErrs[j].Module {
+			return verifyErrs[i].Module < verifyErrs[j].Module
+		}
+		return verifyErrs[i].Version < verifyErrs[j].Version
+	})
+	return verifyErrs
+}
+
 // Program defines the top-level model of packages that will be taken as input by static analyzers.
 type Program struct {
-	pkgSet map[string]*Package // pkgSet is the set of packages loaded in this program
-	module *Module             // module record the information in `go.mod` of program
+	pkgSet   map[string]*Package // pkgSet is the set of packages loaded in this program
+	module   *Module             // module record the information in `go.mod` of program
+	fileSet  *token.FileSet      // fileSet positions the syntax of packages loaded by program
+	importer *progImporter       // importer resolves the imported packages in type checking
+	ssaProg  *ssa.Program        // ssaProg is the SSA form of the whole program, or nil
+	options  *LoadOptions        // options configure the loading of packages, or nil by default
+
+	parsedDirs map[string]*parsedDir // parsedDirs map from directories to syntax not built yet
 }
 
 // goModFileOf returns absolute path of 'go.mod' in current work directory (cwd).
@@ -84,11 +199,11 @@ func goModFileOf(cwd string) (string, error) {
 	for len(cwdPath) > 0 && cwdPath != "/" && cwdPath != "." && cwdPath != ".." {
 		goModFile := filepath.Join(cwdPath, GoModFileName)
 		if _, err := os.Stat(goModFile); !os.IsNotExist(err) {
-			return cwdPath, nil
+			return goModFile, nil
 		}
 		cwdPath = filepath.Dir(cwdPath)
 	}
-	return "", fmt.Errorf("couldn't find `go.mod` file from: %s", cwd)
+	return "", fmt.Errorf("%w from: %s", ErrNoGoMod, cwd)
 }
 
 // initProgram returns initialized Program with module info, or nil if it fails to load the module.
@@ -118,8 +233,10 @@ func initProgram(cwd string) (*Program, error) {
 
 	// 3. return the initialized Program instance
 	return &Program{
-		pkgSet: make(map[string]*Package),
-		module: module,
+		pkgSet:     make(map[string]*Package),
+		module:     module,
+		fileSet:    token.NewFileSet(),
+		parsedDirs: make(map[string]*parsedDir),
 	}, nil
 }
 
@@ -137,6 +254,43 @@ func (prog *Program) AllPackages() []*Package {
 	return nil
 }
 
+// ProgramDiff is the difference of packages between two loads of program, where the packages are
+// identified by their paths and each list is sorted.
+type ProgramDiff struct {
+	Added   []string // Added are the paths of packages only in the other program
+	Removed []string // Removed are the paths of packages only in this program
+	Changed []string // Changed are the paths of packages in both with different Package.Hash
+}
+
+// Diff compares the packages in this program (the old load) with those in the other (the new load)
+// by their paths, and reports the packages added, removed or changed in their contents, such that
+// the analyzers could only run again on the affected packages.
+func (prog *Program) Diff(other *Program) ProgramDiff {
+	var diff ProgramDiff
+	var oldPkgs = make(map[string]*Package)
+	for _, pkg := range prog.AllPackages() {
+		oldPkgs[pkg.pkgPath] = pkg
+	}
+	var newPkgs = make(map[string]*Package)
+	for _, pkg := range other.AllPackages() {
+		newPkgs[pkg.pkgPath] = pkg
+		if oldPkg, ok := oldPkgs[pkg.pkgPath]; !ok {
+			diff.Added = append(diff.Added, pkg.pkgPath)
+		} else if oldPkg.Hash() != pkg.Hash() {
+			diff.Changed = append(diff.Changed, pkg.pkgPath)
+		}
+	}
+	for pkgPath := range oldPkgs {
+		if _, ok := newPkgs[pkgPath]; !ok {
+			diff.Removed = append(diff.Removed, pkgPath)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
 // Module records the module information of go.mod from the program.
 func (prog *Program) Module() *Module {
 	if prog != nil {
@@ -145,6 +299,14 @@ func (prog *Program) Module() *Module {
 	return nil
 }
 
+// FileSet positions the syntax of packages loaded in the program.
+func (prog *Program) FileSet() *token.FileSet {
+	if prog != nil {
+		return prog.fileSet
+	}
+	return nil
+}
+
 // Package return the unique package in program w.r.t. the unique path
 func (prog *Program) Package(pkgPath string) *Package {
 	if prog != nil {
@@ -153,6 +315,39 @@ func (prog *Program) Package(pkgPath string) *Package {
 	return nil
 }
 
+// LoadByPath loads the package of the module by its import path into the program (with the options
+// the program was loaded with), of which directory is resolved under RootPath of the module, or
+// returns the package if it has been loaded. The standard and external packages can't be loaded,
+// which are not in module.
+func (prog *Program) LoadByPath(pkgPath string) (*Package, error) {
+	// 1. resolve the directory of package in module
+	if prog == nil {
+		return nil, fmt.Errorf("nil program is used")
+	}
+	if pkg := prog.Package(pkgPath); pkg.IsLoaded() && !pkg.isCached() {
+		return pkg, nil
+	}
+	dirPath, ok := prog.module.dirOf(pkgPath)
+	if !ok {
+		return nil, fmt.Errorf("not in module (standard or external package): %s", pkgPath)
+	}
+	if dirInfo, err := os.Stat(dirPath); err != nil || !dirInfo.IsDir() {
+		return nil, fmt.Errorf("no directory of package %s: %s", pkgPath, dirPath)
+	}
+
+	// 2. load the packages in directory into program
+	pkgs, loadErr := prog.loadDirectory(dirPath, prog.options, !prog.options.excludesTests())
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	for _, pkg := range pkgs {
+		if pkg.pkgPath == pkgPath {
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("%w of path %s in: %s", ErrNoPackages, pkgPath, dirPath)
+}
+
 // newPackage is an internal method to create package from the program
 func (prog *Program) newPackage(pkgName, pkgPath, dirPath string) *Package {
 	if prog != nil {
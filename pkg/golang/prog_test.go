@@ -0,0 +1,160 @@
+package golang
+
+import (
+	"errors"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProgramLoadByPath(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go":          "package a\n\nimport \"example.com/m/a/sub\"\n\nvar _ = sub.S\n",
+		"a/sub/s.go":      "package sub\n\nvar S int\n",
+		"a/sub/s_test.go": "package sub\n\nfunc helperInTest() {}\n",
+		"b/b_linux.go":    "package b\n\nconst OS = \"linux\"\n",
+		"b/b_windows.go":  "package b\n\nconst OS = \"windows\"\n",
+	})
+	var opts = &LoadOptions{GOOS: "windows", GOARCH: "386", ExcludeTests: true}
+	pkgs, err := loadGoDirectoryByFree(filepath.Join(rootDir, "b"), opts)
+	if err != nil || len(pkgs) != 1 {
+		t.Fatalf("loading b = %v, %v", pkgs, err)
+	}
+	var prog = pkgs[0].Program()
+
+	sub, err := prog.LoadByPath(testModulePath + "/a/sub")
+	if err != nil || !sub.IsLoaded() || sub.PkgPath() != testModulePath+"/a/sub" {
+		t.Fatalf("LoadByPath(a/sub) = %v, %v", sub, err)
+	}
+	if prog.Package(testModulePath+"/a") != nil {
+		t.Errorf("the package importing a/sub is loaded")
+	}
+	if sub.Lookup("helperInTest") != nil {
+		t.Errorf("test file is loaded with ExcludeTests in options")
+	}
+	if got := (*sub.TypeSize()).Sizeof(types.Typ[types.Int]); got != 4 {
+		t.Errorf("sizeof(int) = %d, want 4 of GOARCH=386 in options", got)
+	}
+	if again, err := prog.LoadByPath(testModulePath + "/a/sub"); err != nil || again != sub {
+		t.Errorf("LoadByPath(a/sub) again = %v, %v, want the loaded package", again, err)
+	}
+
+	for _, pkgPath := range []string{"fmt", "github.com/other/mod", testModulePath + "/none"} {
+		if pkg, err := prog.LoadByPath(pkgPath); err == nil {
+			t.Errorf("LoadByPath(%s) = %v, want error", pkgPath, pkg)
+		}
+	}
+	if _, err := prog.LoadByPath(testModulePath); !errors.Is(err, ErrNoPackages) {
+		t.Errorf("LoadByPath(module root) = %v, want ErrNoPackages", err)
+	}
+}
+
+func TestNewModule(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{GoModFileName: `module example.com/m // the module
+
+go 1.20
+
+require (
+	github.com/a/b v1.0.0 // see https://example.com/issue/1
+	// github.com/old/dep v0.1.0
+	github.com/c/d v1.2.0 // indirect
+	github.com/e/f v0.3.0 // indirect; needed by b
+	github.com/g/h v0.4.0 // not indirect
+	github.com/i/j v0.5.0 // see /* here
+	github.com/k/l v0.6.0
+)
+`})
+	module, err := newModule(filepath.Join(rootDir, GoModFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.ModuleName != testModulePath || module.GoVersion != "1.20" {
+		t.Errorf("module = %q, go = %q", module.ModuleName, module.GoVersion)
+	}
+	var wantDirect = map[string]string{
+		"github.com/a/b": "v1.0.0",
+		"github.com/g/h": "v0.4.0",
+		"github.com/i/j": "v0.5.0",
+		"github.com/k/l": "v0.6.0",
+	}
+	var wantIndirect = map[string]string{"github.com/c/d": "v1.2.0", "github.com/e/f": "v0.3.0"}
+	if !reflect.DeepEqual(module.DirectDeps, wantDirect) {
+		t.Errorf("DirectDeps = %v, want %v", module.DirectDeps, wantDirect)
+	}
+	if !reflect.DeepEqual(module.IndirectDeps, wantIndirect) {
+		t.Errorf("IndirectDeps = %v, want %v", module.IndirectDeps, wantIndirect)
+	}
+}
+
+func TestModuleVerifyDeps(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		GoModFileName: "module example.com/m\n\ngo 1.20\n\nrequire (\n" +
+			"\texample.com/a v1.0.0\n\texample.com/b v1.2.0 // indirect\n\texample.com/c v0.1.0\n)\n",
+		GoSumFileName: "example.com/a v1.0.0 h1:zipA=\nexample.com/a v1.0.0/go.mod h1:modA=\n" +
+			"example.com/b v1.2.0/go.mod h1:modB=\nexample.com/c v0.1.0 h1:zipC=\n" +
+			"example.com/d v0.3.0/go.mod h1:modD=\n",
+	})
+	module, err := newModule(filepath.Join(rootDir, GoModFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []VerifyError{
+		{Module: "example.com/c", Version: "v0.1.0", Missing: true},
+		{Module: "example.com/d", Version: "v0.3.0"},
+	}
+	verifyErrs := module.VerifyDeps()
+	if !reflect.DeepEqual(verifyErrs, want) {
+		t.Fatalf("VerifyDeps = %v, want %v", verifyErrs, want)
+	}
+	if got, want := verifyErrs[0].Error(), "missing in go.sum: example.com/c v0.1.0"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if got, want := verifyErrs[1].Error(), "extra in go.sum: example.com/d v0.3.0"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+
+	if err := os.Remove(filepath.Join(rootDir, GoSumFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if got := module.VerifyDeps(); len(got) != 3 {
+		t.Errorf("VerifyDeps without go.sum = %v, want all 3 missing", got)
+	}
+}
+
+func TestProgramDiff(t *testing.T) {
+	var files = map[string]string{
+		"a/a.go": "package a\n\nconst A = 1\n",
+		"b/b.go": "package b\n\nconst B = 2\n",
+		"c/c.go": "package c\n\nconst C = 3\n",
+	}
+	var load = func(files map[string]string) *Program {
+		t.Helper()
+		var copied = make(map[string]string)
+		for name, code := range files {
+			copied[name] = code
+		}
+		prog, err := Load(writeModule(t, copied))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prog
+	}
+	var oldProg = load(files)
+	if diff := oldProg.Diff(load(files)); !reflect.DeepEqual(diff, ProgramDiff{}) {
+		t.Errorf("Diff of copied tree = %+v, want empty", diff)
+	}
+
+	files["b/b.go"] = "package b\n\nconst B = 20\n"
+	files["d/d.go"] = "package d\n\nconst D = 4\n"
+	delete(files, "c/c.go")
+	var want = ProgramDiff{
+		Added:   []string{testModulePath + "/d"},
+		Removed: []string{testModulePath + "/c"},
+		Changed: []string{testModulePath + "/b"},
+	}
+	if diff := oldProg.Diff(load(files)); !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff = %+v, want %+v", diff, want)
+	}
+}
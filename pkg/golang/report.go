@@ -0,0 +1,250 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the reports of Diagnostic findings in the formats consumed by
+// users and tools, such as the SARIF document uploaded to code scanning services.
+package golang
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	sarifVersion  = "2.1.0"                                         // sarifVersion of documents
+	sarifSchema   = "https://json.schemastore.org/sarif-2.1.0.json" // sarifSchema of SARIF 2.1.0
+	sarifToolName = "golintci"                                      // sarifToolName in the runs
+	sarifToolURI  = "https://github.com/yukimula918/golintci"       // sarifToolURI of the tool
+	sarifRootID   = "%SRCROOT%"                                     // sarifRootID for relative URIs
+	sarifNoRuleID = "golintci"                                      // sarifNoRuleID if no category
+)
+
+// sarifLog is the root object of SARIF document, with the subset of properties used by golintci.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun is a run of the tool producing the results.
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+// sarifTool describes the tool as its driver with the rules.
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+// sarifRule is the rule derived from the category of diagnostics.
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+// sarifMessage is the text of message in SARIF.
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is a result of the run, i.e. a diagnostic.
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+// sarifLocation is the physical location of a result in the source file.
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI       string `json:"uri"`
+			URIBaseID string `json:"uriBaseId,omitempty"`
+		} `json:"artifactLocation"`
+		Region sarifRegion `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// sarifRegion is the range of code in the source file, with 1-based lines and columns.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// sarifLevelOf returns the SARIF level of the severity.
+func sarifLevelOf(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// positionOf resolves the position in the FileSet of program, or the one of any package in which
+// the position is found if the program's FileSet doesn't contain it (e.g. virtual packages).
+func (prog *Program) positionOf(pos token.Pos) token.Position {
+	if prog == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	if prog.fileSet != nil && prog.fileSet.File(pos) != nil {
+		return prog.fileSet.Position(pos)
+	}
+	for _, pkg := range prog.AllPackages() {
+		if pkg.fileSet != nil && pkg.fileSet.File(pos) != nil {
+			return pkg.fileSet.Position(pos)
+		}
+	}
+	return token.Position{}
+}
+
+// ExportSARIF writes the diagnostics as a SARIF 2.1.0 document to the writer, in which the rules are
+// derived from the categories of diagnostics, and the locations of results are resolved by FileSet
+// of the program using the paths relative to the root of module (Module.RootPath).
+func ExportSARIF(prog *Program, diags []Diagnostic, w io.Writer) error {
+	if w == nil {
+		return fmt.Errorf("nil writer is used")
+	}
+	var rootPath string
+	if module := prog.Module(); module != nil {
+		rootPath = module.RootPath
+	}
+
+	// 1. derive the rules from categories of diagnostics
+	var ruleIndex = make(map[string]int)
+	var categories []string
+	for _, diag := range diags {
+		category := diag.Category
+		if len(category) == 0 {
+			category = sarifNoRuleID
+		}
+		if _, ok := ruleIndex[category]; !ok {
+			ruleIndex[category] = -1
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	var run = sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = sarifToolName
+	run.Tool.Driver.InformationURI = sarifToolURI
+	run.Tool.Driver.Rules = []sarifRule{}
+	for index, category := range categories {
+		ruleIndex[category] = index
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules,
+			sarifRule{ID: category, ShortDescription: sarifMessage{Text: category}})
+	}
+
+	// 2. construct the results with physical locations
+	for _, diag := range diags {
+		category := diag.Category
+		if len(category) == 0 {
+			category = sarifNoRuleID
+		}
+		result := sarifResult{
+			RuleID:    category,
+			RuleIndex: ruleIndex[category],
+			Level:     sarifLevelOf(diag.Severity),
+			Message:   sarifMessage{Text: diag.Message},
+		}
+		if start := prog.positionOf(diag.Pos); start.IsValid() {
+			var location sarifLocation
+			location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(start.Filename)
+			if len(rootPath) > 0 && filepath.IsAbs(start.Filename) {
+				if relPath, err := filepath.Rel(rootPath, start.Filename); err == nil {
+					location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(relPath)
+					location.PhysicalLocation.ArtifactLocation.URIBaseID = sarifRootID
+				}
+			}
+			location.PhysicalLocation.Region = sarifRegion{StartLine: start.Line, StartColumn: start.Column}
+			if end := prog.positionOf(diag.End); end.IsValid() && end.Filename == start.Filename {
+				location.PhysicalLocation.Region.EndLine = end.Line
+				location.PhysicalLocation.Region.EndColumn = end.Column
+			}
+			result.Locations = append(result.Locations, location)
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	// 3. encode the SARIF document into the writer
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// FormatDiagnostics writes the diagnostics as the text report to the writer, in which findings are
+// listed as `path:line:col: [category] message` grouped by files and sorted by positions in them,
+// followed by a summary of the counts. The positions are resolved by the FileSet.
+func FormatDiagnostics(w io.Writer, diags []Diagnostic, fset *token.FileSet) error {
+	return FormatDiagnosticsIn(w, diags, fset, "")
+}
+
+// FormatDiagnosticsIn writes the text report of diagnostics like FormatDiagnostics, of which paths
+// are relative to the base directory if it is not empty.
+func FormatDiagnosticsIn(w io.Writer, diags []Diagnostic, fset *token.FileSet, baseDir string) error {
+	// 1. resolve and sort the positions of diagnostics
+	type located struct {
+		diag     Diagnostic
+		position token.Position
+	}
+	var items = make([]located, 0, len(diags))
+	for _, diag := range diags {
+		var position token.Position
+		if fset != nil && diag.Pos.IsValid() && fset.File(diag.Pos) != nil {
+			position = fset.Position(diag.Pos)
+		}
+		if len(baseDir) > 0 && filepath.IsAbs(position.Filename) {
+			if relPath, err := filepath.Rel(baseDir, position.Filename); err == nil {
+				position.Filename = relPath
+			}
+		}
+		items = append(items, located{diag: diag, position: position})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		pi, pj := items[i].position, items[j].position
+		if pi.IsValid() != pj.IsValid() {
+			return pi.IsValid() // the ones without position at last
+		} else if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+
+	// 2. write each finding in lines grouped by file
+	var files = make(map[string]bool)
+	var counts = make(map[Severity]int)
+	for _, item := range items {
+		var location = "-"
+		if item.position.IsValid() {
+			location = fmt.Sprintf("%s:%d:%d", item.position.Filename, item.position.Line, item.position.Column)
+			files[item.position.Filename] = true
+		}
+		var category string
+		if len(item.diag.Category) > 0 {
+			category = fmt.Sprintf("[%s] ", item.diag.Category)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s%s\n", location, category, item.diag.Message); err != nil {
+			return err
+		}
+		counts[item.diag.Severity]++
+	}
+
+	// 3. write the summary of counts at last
+	_, err := fmt.Fprintf(w, "%d diagnostics (%d errors, %d warnings, %d infos) in %d files\n",
+		len(items), counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo], len(files))
+	return err
+}
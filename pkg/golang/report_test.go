@@ -0,0 +1,99 @@
+package golang
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares the output with the golden file in testdata, or updates the file by output if
+// the flag -update is set.
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	var goldenFile = filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(goldenFile, output, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, golden) {
+		t.Errorf("output differs from %s:\n%s\nwant:\n%s", goldenFile, output, golden)
+	}
+}
+
+// reportFixture loads a module of two packages, and returns its program along with the diagnostics
+// reported on them.
+func reportFixture(t *testing.T) (*Program, []Diagnostic) {
+	t.Helper()
+	const aCode = "package a\n\nfunc A() {\n\tvar unused int\n}\n"
+	const bCode = "package b\n\nimport \"fmt\"\n\nfunc B(err error) error { return fmt.Errorf(\"b: %v\", err) }\n"
+	rootDir := writeModule(t, map[string]string{"a/a.go": aCode, "b/b.go": bCode})
+	prog, err := Load(rootDir)
+	if prog == nil {
+		t.Fatalf("Load(%s) = nil, %v", rootDir, err)
+	}
+	var posOf = func(pkgPath, srcPath, code, text string) token.Pos {
+		pkg := prog.Package(testModulePath + "/" + pkgPath)
+		srcFile := pkg.SrcFile(filepath.Join(rootDir, filepath.FromSlash(srcPath)))
+		return token.Pos(pkg.FileSet().File(srcFile.Syntax().Pos()).Base() + strings.Index(code, text))
+	}
+	var unused = posOf("a", "a/a.go", aCode, "unused")
+	var errorf = posOf("b", "b/b.go", bCode, "fmt.Errorf")
+	return prog, []Diagnostic{
+		{Pos: errorf, End: errorf + 10, Category: "errorwrap", Message: "use %w to wrap err", Severity: SeverityWarning},
+		{Pos: unused, End: unused + 6, Category: "unused", Message: "unused declared and not used", Severity: SeverityError},
+		{Pos: unused + 1, Category: "", Message: "spelling of unused", Severity: SeverityInfo},
+		{Pos: token.NoPos, Category: "unused", Message: "no position", Severity: SeverityInfo},
+	}
+}
+
+func TestExportSARIF(t *testing.T) {
+	prog, diags := reportFixture(t)
+	var buffer bytes.Buffer
+	if err := ExportSARIF(prog, diags, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &document); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if document["version"] != "2.1.0" || len(document["runs"].([]any)) != 1 {
+		t.Errorf("document is not SARIF 2.1.0 of one run: %v", document)
+	}
+	checkGolden(t, "report.sarif", buffer.Bytes())
+
+	if err := ExportSARIF(prog, diags, nil); err == nil {
+		t.Errorf("ExportSARIF(nil writer) = nil, want error")
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	prog, diags := reportFixture(t)
+	var buffer bytes.Buffer
+	if err := FormatDiagnosticsIn(&buffer, diags, prog.FileSet(), prog.Module().RootPath); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "report.txt", buffer.Bytes())
+
+	// the paths are absolute without base directory
+	buffer.Reset()
+	if err := FormatDiagnostics(&buffer, diags[:1], prog.FileSet()); err != nil {
+		t.Fatal(err)
+	}
+	var want = filepath.Join(prog.Module().RootPath, "b", "b.go") + ":5:34: [errorwrap] use %w to wrap err\n" +
+		"1 diagnostics (0 errors, 1 warnings, 0 infos) in 1 files\n"
+	if buffer.String() != want {
+		t.Errorf("FormatDiagnostics = %q, want %q", buffer.String(), want)
+	}
+}
@@ -0,0 +1,619 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the queries on the package-level scope of Package, which are
+// derived from the types.Package and types.Info produced in type checking its source files.
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GlobalVar is a package-level variable along with the position where it is declared in the code.
+type GlobalVar struct {
+	Obj *types.Var     // Obj is the variable object declared in the package scope
+	Pos token.Position // Pos is the position of the variable's declaration in source
+}
+
+// isSharedStateType checks whether the type is intended to be shared among goroutines, such as the
+// sync.Mutex, sync.RWMutex or any channel type.
+func isSharedStateType(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if _, ok := typ.Underlying().(*types.Chan); ok {
+		return true
+	}
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		if obj != nil && obj.Pkg() != nil && obj.Pkg().Path() == "sync" {
+			return obj.Name() == "Mutex" || obj.Name() == "RWMutex"
+		}
+	}
+	return false
+}
+
+// MutableGlobals returns the non-constant package-level variables, which are common sources of the
+// data races, excluding those of sync.Mutex, sync.RWMutex and channels as intentional shared state.
+func (pkg *Package) MutableGlobals() []GlobalVar {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil
+	}
+	var globals []GlobalVar
+	scope := pkg.typePkg.Scope()
+	for _, name := range scope.Names() {
+		variable, ok := scope.Lookup(name).(*types.Var)
+		if !ok || variable == nil || isSharedStateType(variable.Type()) {
+			continue
+		}
+		var pos token.Position
+		if pkg.fileSet != nil {
+			pos = pkg.fileSet.Position(variable.Pos())
+		}
+		globals = append(globals, GlobalVar{Obj: variable, Pos: pos})
+	}
+	return globals
+}
+
+// namedTypeOf returns the named type declared in the package scope with the name, or nil if it is
+// not found or the package is not type-checked.
+func (pkg *Package) namedTypeOf(typeName string) *types.Named {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil
+	}
+	typeObj, ok := pkg.typePkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok || typeObj == nil {
+		return nil
+	}
+	named, _ := typeObj.Type().(*types.Named)
+	return named
+}
+
+// methodsOfSet returns the functions in the method set of the type.
+func methodsOfSet(typ types.Type) []*types.Func {
+	var methods []*types.Func
+	methodSet := types.NewMethodSet(typ)
+	for i := 0; i < methodSet.Len(); i++ {
+		if method, ok := methodSet.At(i).Obj().(*types.Func); ok {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// MethodsOf returns the methods in the method set of the named type T declared in the package with
+// typeName, including those promoted from its embedded fields, or nil if the type is not found. Use
+// ReceiverMethods to include the methods in the method set of *T as well.
+func (pkg *Package) MethodsOf(typeName string) []*types.Func {
+	named := pkg.namedTypeOf(typeName)
+	if named == nil {
+		return nil
+	}
+	return methodsOfSet(named)
+}
+
+// ReceiverMethods returns the methods in the method sets of both T and *T, where T is the named type
+// declared in the package with typeName, or nil if the type is not found.
+func (pkg *Package) ReceiverMethods(typeName string) []*types.Func {
+	named := pkg.namedTypeOf(typeName)
+	if named == nil {
+		return nil
+	}
+	var methods []*types.Func
+	var visited = make(map[*types.Func]bool)
+	for _, typ := range []types.Type{named, types.NewPointer(named)} {
+		for _, method := range methodsOfSet(typ) {
+			if !visited[method] {
+				visited[method] = true
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods
+}
+
+// PointerReceiverMethods returns the methods in method set of *T but not in the one of T, where T is
+// the named type declared in the package with typeName, or nil if the type is not found.
+func (pkg *Package) PointerReceiverMethods(typeName string) []*types.Func {
+	named := pkg.namedTypeOf(typeName)
+	if named == nil {
+		return nil
+	}
+	var valueMethods = make(map[*types.Func]bool)
+	for _, method := range methodsOfSet(named) {
+		valueMethods[method] = true
+	}
+	var methods []*types.Func
+	for _, method := range methodsOfSet(types.NewPointer(named)) {
+		if !valueMethods[method] {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// StructSizeInfo is a named struct type declared in the package along with its size and alignment.
+type StructSizeInfo struct {
+	Type  *types.Named // Type is the named type of which the underlying type is a struct
+	Size  int64        // Size is the number of bytes taken by a variable of the struct type
+	Align int64        // Align is the alignment in bytes of a variable of the struct type
+}
+
+// sizesOf returns the sizes used to type-check the package, or those of the default architecture.
+func (pkg *Package) sizesOf() types.Sizes {
+	if pkg != nil && pkg.typSize != nil && *pkg.typSize != nil {
+		return *pkg.typSize
+	}
+	return types.SizesFor("gc", build.Default.GOARCH)
+}
+
+// sizeAndAlignOf computes the size and alignment of the type, or returns false if the computation
+// fails, e.g. the sizes panic on the invalid types in the ill-typed packages.
+func sizeAndAlignOf(typ types.Type, sizes types.Sizes) (size, align int64, ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			size, align, ok = 0, 0, false
+		}
+	}()
+	if typ == nil || sizes == nil {
+		return 0, 0, false
+	}
+	return sizes.Sizeof(typ), sizes.Alignof(typ), true
+}
+
+// LargeStructs returns the named struct types declared in the package scope, of which the size is
+// at least minBytes, in the order of their names. It returns nil if the package isn't type-checked.
+func (pkg *Package) LargeStructs(minBytes int) []StructSizeInfo {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil
+	}
+	var structs []StructSizeInfo
+	var sizes = pkg.sizesOf()
+	scope := pkg.typePkg.Scope()
+	for _, name := range scope.Names() {
+		typeObj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeObj == nil || typeObj.IsAlias() {
+			continue
+		}
+		named, ok := typeObj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue // the size of generic type depends on its instantiation
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		size, align, ok := sizeAndAlignOf(named, sizes)
+		if ok && size >= int64(minBytes) {
+			structs = append(structs, StructSizeInfo{Type: named, Size: size, Align: align})
+		}
+	}
+	return structs
+}
+
+// Lookup returns the object declared in the package scope with the name, or nil if it is not found
+// or the package is not type-checked.
+func (pkg *Package) Lookup(name string) types.Object {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil
+	}
+	return pkg.typePkg.Scope().Lookup(name)
+}
+
+// Names returns the names of objects declared in the package scope in sorted order, or nil if the
+// package is not type-checked.
+func (pkg *Package) Names() []string {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil
+	}
+	return pkg.typePkg.Scope().Names()
+}
+
+// isHandlerSignature checks whether the signature is `func(http.ResponseWriter, *http.Request)`,
+// i.e. the one of http.HandlerFunc.
+func isHandlerSignature(signature *types.Signature) bool {
+	if signature == nil || signature.Params().Len() != 2 || signature.Results().Len() != 0 ||
+		signature.Variadic() {
+		return false
+	}
+	if !isNamedTypeOf(signature.Params().At(0).Type(), "net/http", "ResponseWriter") {
+		return false
+	}
+	request, ok := signature.Params().At(1).Type().(*types.Pointer)
+	return ok && isNamedTypeOf(request.Elem(), "net/http", "Request")
+}
+
+// HttpHandlerSignatures returns the package-level functions with the signature of http.HandlerFunc,
+// i.e. `func(http.ResponseWriter, *http.Request)`, in the order of their names.
+func (pkg *Package) HttpHandlerSignatures() []*types.Func {
+	var handlers []*types.Func
+	for _, name := range pkg.Names() {
+		function, ok := pkg.Lookup(name).(*types.Func)
+		if !ok || function == nil {
+			continue
+		}
+		if signature, ok := function.Type().(*types.Signature); ok && isHandlerSignature(signature) {
+			handlers = append(handlers, function)
+		}
+	}
+	return handlers
+}
+
+// Functions returns the package-level functions followed by the methods declared on each named type
+// in the package (in the order of names in scope), excluding the methods promoted from embedded
+// fields. It returns nil if the package is not type-checked.
+func (pkg *Package) Functions() []*types.Func {
+	var functions []*types.Func
+	for _, name := range pkg.Names() {
+		switch obj := pkg.Lookup(name).(type) {
+		case *types.Func:
+			functions = append(functions, obj)
+		case *types.TypeName:
+			if named, ok := obj.Type().(*types.Named); ok && !obj.IsAlias() {
+				for i := 0; i < named.NumMethods(); i++ {
+					functions = append(functions, named.Method(i))
+				}
+			}
+		}
+	}
+	return functions
+}
+
+// FunctionsReturningError returns the package-level functions and methods declared in this package,
+// of which any result type implements the error interface, sorted by their names (and then by the
+// full names for methods of the same name), or nil if the package is not type-checked.
+func (pkg *Package) FunctionsReturningError() []*types.Func {
+	var functions []*types.Func
+	var visited = make(map[*types.Func]bool)
+	for _, function := range pkg.Functions() {
+		signature, ok := function.Type().(*types.Signature)
+		if !ok || visited[function] {
+			continue
+		}
+		for i := 0; i < signature.Results().Len(); i++ {
+			if isErrorType(signature.Results().At(i).Type()) {
+				visited[function] = true
+				functions = append(functions, function)
+				break
+			}
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Name() != functions[j].Name() {
+			return functions[i].Name() < functions[j].Name()
+		}
+		return functions[i].FullName() < functions[j].FullName()
+	})
+	return functions
+}
+
+// FuncSignatures maps the functions and methods declared in this source file to their signatures as
+// declared, e.g. `func (pkg *Package) SrcFile(path string) *SrcFile`, where the types of this package
+// are unqualified. The methods are keyed with their receiver types, e.g. `(*Package).SrcFile` and
+// `VerifyError.Error`. It returns nil if the package is not type-checked.
+func (file *SrcFile) FuncSignatures() map[string]string {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.typInfo == nil {
+		return nil
+	}
+	var qualifier = types.RelativeTo(file.pkg.typePkg)
+	var signatures = make(map[string]string)
+	for _, funcDecl := range file.Functions() {
+		function, ok := file.pkg.typInfo.Defs[funcDecl.Name].(*types.Func)
+		if !ok || !file.Contain(function.Pos()) {
+			continue
+		}
+		signature, ok := function.Type().(*types.Signature)
+		if !ok {
+			continue
+		}
+		var key, receiver = function.Name(), ""
+		if recv := signature.Recv(); recv != nil {
+			recvType := types.TypeString(recv.Type(), qualifier)
+			if _, isPointer := recv.Type().(*types.Pointer); isPointer {
+				key = fmt.Sprintf("(%s).%s", recvType, function.Name())
+			} else {
+				key = fmt.Sprintf("%s.%s", recvType, function.Name())
+			}
+			if len(recv.Name()) > 0 && recv.Name() != "_" {
+				recvType = recv.Name() + SpaceChar + recvType
+			}
+			receiver = fmt.Sprintf("(%s) ", recvType)
+		}
+		var params = strings.TrimPrefix(types.TypeString(signature, qualifier), "func")
+		signatures[key] = fmt.Sprintf("func %s%s%s", receiver, function.Name(), params)
+	}
+	return signatures
+}
+
+// NamedTypes returns the named types defined in the package scope (e.g. `type T struct{}`) in the
+// order of their names, excluding the type aliases which are returned by Aliases.
+func (pkg *Package) NamedTypes() []*types.Named {
+	var namedTypes []*types.Named
+	for _, name := range pkg.Names() {
+		typeObj, ok := pkg.Lookup(name).(*types.TypeName)
+		if !ok || typeObj == nil || typeObj.IsAlias() {
+			continue
+		}
+		if named, ok := typeObj.Type().(*types.Named); ok {
+			namedTypes = append(namedTypes, named)
+		}
+	}
+	return namedTypes
+}
+
+// Aliases returns the type aliases declared in the package scope (e.g. `type A = B`) in the order of
+// their names.
+func (pkg *Package) Aliases() []*types.TypeName {
+	var aliases []*types.TypeName
+	for _, name := range pkg.Names() {
+		if typeObj, ok := pkg.Lookup(name).(*types.TypeName); ok && typeObj != nil && typeObj.IsAlias() {
+			aliases = append(aliases, typeObj)
+		}
+	}
+	return aliases
+}
+
+// ObjectOf returns the object defined or used by the identifier in the package, or nil if it is not
+// found or the package is not type-checked.
+func (pkg *Package) ObjectOf(ident *ast.Ident) types.Object {
+	if pkg == nil || pkg.typInfo == nil || ident == nil {
+		return nil
+	}
+	return pkg.typInfo.ObjectOf(ident)
+}
+
+// TypeOf returns the type of expression in the package, or nil if it is not found or the package is
+// not type-checked.
+func (pkg *Package) TypeOf(expr ast.Expr) types.Type {
+	if pkg == nil || pkg.typInfo == nil || expr == nil {
+		return nil
+	}
+	return pkg.typInfo.TypeOf(expr)
+}
+
+// resolveTypeName resolves the type declared in the package scope by name (e.g. "T"), or declared
+// in another package qualified by its import path (e.g. "io.Reader" or "net/http.Handler"), which
+// is found in the (transitive) imports of package or imported by the importer type-checking it,
+// such that the types resolved are identical to those referred in the package.
+func (pkg *Package) resolveTypeName(name string) (*types.TypeName, error) {
+	if pkg == nil || pkg.typePkg == nil {
+		return nil, fmt.Errorf("package not type-checked: %s", pkg.PkgPath())
+	}
+	var typePkg = pkg.typePkg
+	var dot = strings.LastIndex(name, ".")
+	if dot >= 0 {
+		var importPath = name[:dot]
+		name = name[dot+1:]
+		typePkg = importedTypePkgOf(pkg.typePkg, importPath)
+		if typePkg == nil || typePkg.Scope().Lookup(name) == nil {
+			// the indirect imports might be incomplete, which are completed by the same importer
+			var importer = pkg.importer
+			if importer == nil {
+				importer = pkg.program.importerOf(pkg.options)
+			}
+			imported, err := importer.Import(importPath)
+			if err != nil {
+				return nil, err
+			}
+			typePkg = imported
+		}
+	}
+	typeObj, ok := typePkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok || typeObj == nil {
+		return nil, fmt.Errorf("type not found: %s.%s", typePkg.Path(), name)
+	}
+	return typeObj, nil
+}
+
+// importedTypePkgOf returns the package of import path imported by the package directly or through
+// its imports, or nil if it isn't imported.
+func importedTypePkgOf(typePkg *types.Package, importPath string) *types.Package {
+	var visited = map[*types.Package]bool{typePkg: true}
+	var queue = []*types.Package{typePkg}
+	for len(queue) > 0 {
+		for _, imported := range queue[0].Imports() {
+			if imported.Path() == importPath {
+				return imported
+			}
+			if !visited[imported] {
+				visited[imported] = true
+				queue = append(queue, imported)
+			}
+		}
+		queue = queue[1:]
+	}
+	return nil
+}
+
+// Implements checks whether the type T (or *T) named by typeName implements the interface named by
+// ifaceName, both of which are declared in this package by name or qualified by the import paths
+// of other packages (e.g. "io.Reader"). It returns error if either one can't be resolved.
+func (pkg *Package) Implements(typeName, ifaceName string) (bool, error) {
+	typeObj, err := pkg.resolveTypeName(typeName)
+	if err != nil {
+		return false, err
+	}
+	ifaceObj, err := pkg.resolveTypeName(ifaceName)
+	if err != nil {
+		return false, err
+	}
+	iface, ok := ifaceObj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return false, fmt.Errorf("not an interface: %s", ifaceName)
+	}
+	if types.Implements(typeObj.Type(), iface) {
+		return true, nil
+	}
+	if _, isIface := typeObj.Type().Underlying().(*types.Interface); isIface {
+		return false, nil // pointers to interfaces have no methods
+	}
+	return types.Implements(types.NewPointer(typeObj.Type()), iface), nil
+}
+
+// DeadSymbol is an object declared in the package scope but never referenced.
+type DeadSymbol struct {
+	Object types.Object // Object is the object declared in the package scope
+	Reason string       // Reason describes why the object is considered as dead
+}
+
+// DeadCode returns the unexported objects (functions, types, variables and constants) declared in
+// the package scope, which are never used in the package, in the order of their names. The init
+// functions and the main function of main package are excluded as they are called by runtime.
+//
+// The exported objects are excluded as well, since they might be used by other packages, which is
+// not known at the scope of single package. It returns nil if the package is not type-checked.
+func (pkg *Package) DeadCode() []DeadSymbol {
+	if pkg == nil || pkg.typePkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var referenced = make(map[types.Object]bool)
+	for _, obj := range pkg.typInfo.Uses {
+		referenced[obj] = true
+	}
+	var symbols []DeadSymbol
+	for _, name := range pkg.Names() {
+		obj := pkg.Lookup(name)
+		if obj == nil || obj.Exported() || name == "_" || name == "init" ||
+			(name == "main" && pkg.typePkg.Name() == "main") || referenced[obj] {
+			continue
+		}
+		var kind = "object"
+		switch obj.(type) {
+		case *types.Func:
+			kind = "function"
+		case *types.TypeName:
+			kind = "type"
+		case *types.Var:
+			kind = "variable"
+		case *types.Const:
+			kind = "constant"
+		}
+		symbols = append(symbols, DeadSymbol{
+			Object: obj,
+			Reason: fmt.Sprintf("unexported %s %s is never used", kind, name),
+		})
+	}
+	return symbols
+}
+
+// UsedTypes returns the unique types of expressions in this package recorded in types.Info, which
+// are deduplicated by their string representations and sorted by them. The invalid types of the
+// ill-typed expressions are excluded.
+func (pkg *Package) UsedTypes() []types.Type {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	var typeMap = make(map[string]types.Type)
+	for _, typeAndValue := range pkg.typInfo.Types {
+		typ := typeAndValue.Type
+		if typ == nil || typ == types.Typ[types.Invalid] {
+			continue
+		}
+		if _, ok := typeMap[typ.String()]; !ok {
+			typeMap[typ.String()] = typ
+		}
+	}
+	var typeNames = make([]string, 0, len(typeMap))
+	for typeName := range typeMap {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+	var usedTypes = make([]types.Type, 0, len(typeNames))
+	for _, typeName := range typeNames {
+		usedTypes = append(usedTypes, typeMap[typeName])
+	}
+	return usedTypes
+}
+
+// UsedTypeNames returns the string representations of UsedTypes in sorted order, e.g. "[]byte" and
+// "*net/http.Request", which are qualified by the full paths of packages.
+func (pkg *Package) UsedTypeNames() []string {
+	var usedTypes = pkg.UsedTypes()
+	var typeNames = make([]string, 0, len(usedTypes))
+	for _, typ := range usedTypes {
+		typeNames = append(typeNames, typ.String())
+	}
+	return typeNames
+}
+
+// declDocsOf returns the map from positions of identifiers declared at package level in this package
+// to their doc comments, where a spec in an ungrouped declaration takes the doc of the declaration.
+func (pkg *Package) declDocsOf() map[token.Pos]*ast.CommentGroup {
+	var docs = make(map[token.Pos]*ast.CommentGroup)
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, decl := range srcFile.syntax.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					docs[decl.Name.Pos()] = decl.Doc
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					var doc *ast.CommentGroup
+					var names []*ast.Ident
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						doc, names = spec.Doc, []*ast.Ident{spec.Name}
+					case *ast.ValueSpec:
+						doc, names = spec.Doc, spec.Names
+					}
+					if doc == nil && !decl.Lparen.IsValid() {
+						doc = decl.Doc
+					}
+					for _, name := range names {
+						docs[name.Pos()] = doc
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// isDocOf checks whether the doc comment starts with the name of identifier by the conventions of
+// Go doc comments, optionally after an article, e.g. "Foo returns..." or "A Foo is...".
+func isDocOf(doc *ast.CommentGroup, name string) bool {
+	if doc == nil {
+		return false
+	}
+	var text = strings.TrimSpace(doc.Text())
+	for _, article := range []string{"A ", "An ", "The "} {
+		text = strings.TrimPrefix(text, article)
+	}
+	if !strings.HasPrefix(text, name) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(text[len(name):])
+	return next == utf8.RuneError || !(unicode.IsLetter(next) || unicode.IsDigit(next) || next == '_')
+}
+
+// DocumentedExports measures the documentation coverage of API in this package, which counts the
+// exported package-level objects in scope, and those documented by a doc comment starting with the
+// name, along with the ratio of them (1 if there is no exported object).
+func (pkg *Package) DocumentedExports() (documented, total int, ratio float64) {
+	if pkg == nil || pkg.typePkg == nil {
+		return 0, 0, 1
+	}
+	var docs = pkg.declDocsOf()
+	for _, name := range pkg.Names() {
+		obj := pkg.Lookup(name)
+		if obj == nil || !obj.Exported() {
+			continue
+		}
+		total++
+		if isDocOf(docs[obj.Pos()], name) {
+			documented++
+		}
+	}
+	if total == 0 {
+		return documented, total, 1
+	}
+	return documented, total, float64(documented) / float64(total)
+}
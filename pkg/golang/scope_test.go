@@ -0,0 +1,535 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mustVirtualPackage creates the virtual package p of the files, or fails the test.
+func mustVirtualPackage(t *testing.T, files map[string]string) *Package {
+	t.Helper()
+	pkg, err := NewVirtualPackage("p", "example.com/p", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.LoadInfo().HasErrors() {
+		t.Fatalf("virtual package is ill-typed: %v", pkg.LoadInfo().AllErrors())
+	}
+	return pkg
+}
+
+func TestMutableGlobals(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "sync"
+
+const Limit = 10
+
+var (
+	counter int
+	names   = []string{"a"}
+	mu      sync.Mutex
+	rwMu    sync.RWMutex
+	events  = make(chan int)
+	guarded = &sync.Mutex{}
+)
+
+func f() { var local int; _ = local }
+`})
+	var names []string
+	for _, global := range pkg.MutableGlobals() {
+		names = append(names, global.Obj.Name())
+		if !global.Pos.IsValid() || global.Pos.Filename != "p.go" {
+			t.Errorf("%s: position = %v", global.Obj.Name(), global.Pos)
+		}
+	}
+	if want := []string{"counter", "guarded", "names"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("MutableGlobals() = %v, want %v", names, want)
+	}
+	if globals := (*Package)(nil).MutableGlobals(); globals != nil {
+		t.Errorf("MutableGlobals() of nil package = %v", globals)
+	}
+}
+
+// funcNamesOf returns the sorted names of the functions.
+func funcNamesOf(funcs []*types.Func) []string {
+	var names []string
+	for _, fn := range funcs {
+		names = append(names, fn.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReceiverMethods(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type T struct{}
+
+func (T) Get() int   { return 0 }
+func (T) Name() string { return "" }
+func (*T) Set(int)   {}
+
+type Alias = int
+`})
+	if got := funcNamesOf(pkg.ReceiverMethods("T")); !reflect.DeepEqual(got, []string{"Get", "Name", "Set"}) {
+		t.Errorf("ReceiverMethods(T) = %v, want [Get Name Set]", got)
+	}
+	if got := funcNamesOf(pkg.PointerReceiverMethods("T")); !reflect.DeepEqual(got, []string{"Set"}) {
+		t.Errorf("PointerReceiverMethods(T) = %v, want [Set]", got)
+	}
+	for _, typeName := range []string{"Undefined", "Alias"} {
+		if got := pkg.ReceiverMethods(typeName); got != nil {
+			t.Errorf("ReceiverMethods(%s) = %v, want nil", typeName, got)
+		}
+		if got := pkg.PointerReceiverMethods(typeName); got != nil {
+			t.Errorf("PointerReceiverMethods(%s) = %v, want nil", typeName, got)
+		}
+	}
+	var unchecked = newPackage(nil, "p", "example.com/p", "")
+	if unchecked.ReceiverMethods("T") != nil || unchecked.PointerReceiverMethods("T") != nil {
+		t.Errorf("methods are found without type checking")
+	}
+}
+
+func TestLargeStructs(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{"p/p.go": `package p
+
+type Small struct{ a, b int32 }
+
+type Large struct {
+	a, b, c int64
+	d       [4]byte
+}
+
+type Pointers struct{ a, b *int }
+
+type Generic[T any] struct{ v [8]T }
+
+type Int int
+`})
+	for _, test := range []struct {
+		goarch   string
+		minBytes int
+		want     []string
+	}{
+		{"amd64", 0, []string{"Large:32:8", "Pointers:16:8", "Small:8:4"}},
+		{"amd64", 16, []string{"Large:32:8", "Pointers:16:8"}},
+		{"386", 16, []string{"Large:28:4"}},
+		{"amd64", 64, nil},
+	} {
+		prog, err := Load(rootDir, WithGOARCH(test.goarch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, info := range mustPackage(t, prog, testModulePath+"/p").LargeStructs(test.minBytes) {
+			got = append(got, fmt.Sprintf("%s:%d:%d", info.Type.Obj().Name(), info.Size, info.Align))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("LargeStructs(%d) on %s = %v, want %v", test.minBytes, test.goarch, got, test.want)
+		}
+	}
+	var unchecked = newPackage(nil, "p", "example.com/p", "")
+	if got := unchecked.LargeStructs(0); got != nil {
+		t.Errorf("LargeStructs without type checking = %v, want nil", got)
+	}
+}
+
+func TestPackageLookup(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type Server struct{}
+
+func NewServer() *Server { return &Server{} }
+
+var limit = 1
+`})
+	if _, ok := pkg.Lookup("NewServer").(*types.Func); !ok {
+		t.Errorf("Lookup(NewServer) = %v, want a function", pkg.Lookup("NewServer"))
+	}
+	if _, ok := pkg.Lookup("Server").(*types.TypeName); !ok {
+		t.Errorf("Lookup(Server) = %v, want a type name", pkg.Lookup("Server"))
+	}
+	if obj := pkg.Lookup("Undefined"); obj != nil {
+		t.Errorf("Lookup(Undefined) = %v, want nil", obj)
+	}
+	if got, want := pkg.Names(), []string{"NewServer", "Server", "limit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+	var unchecked = newPackage(nil, "p", "example.com/p", "")
+	if unchecked.Lookup("Server") != nil || unchecked.Names() != nil {
+		t.Errorf("names are found without type checking")
+	}
+}
+
+func TestHttpHandlerSignatures(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "net/http"
+
+type Handler struct{}
+
+func (Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func Index(w http.ResponseWriter, r *http.Request) {}
+
+func Health(http.ResponseWriter, *http.Request) {}
+
+func ByValue(w http.ResponseWriter, r http.Request) {}
+
+func WithError(w http.ResponseWriter, r *http.Request) error { return nil }
+
+var Closure = func(w http.ResponseWriter, r *http.Request) {}
+`})
+	if got, want := funcNamesOf(pkg.HttpHandlerSignatures()), []string{"Health", "Index"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HttpHandlerSignatures = %v, want %v", got, want)
+	}
+}
+
+func TestPackageFunctions(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type Base struct{}
+
+func (Base) Promoted() {}
+
+type T struct{ Base }
+
+func (T) Get() int { return 0 }
+
+func (*T) Set(int) {}
+
+func Free() {}
+`})
+	var got []string
+	for _, function := range pkg.Functions() {
+		got = append(got, function.FullName())
+	}
+	var want = []string{
+		"(example.com/p.Base).Promoted",
+		"example.com/p.Free",
+		"(example.com/p.T).Get",
+		"(*example.com/p.T).Set",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Functions = %v, want %v", got, want)
+	}
+}
+
+func TestPackageNamedTypes(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type B struct{}
+
+type A = B
+
+type I interface{ M() }
+
+type N int
+
+type S = string
+`})
+	var named []string
+	for _, typ := range pkg.NamedTypes() {
+		named = append(named, typ.Obj().Name())
+	}
+	if want := []string{"B", "I", "N"}; !reflect.DeepEqual(named, want) {
+		t.Errorf("NamedTypes = %v, want %v", named, want)
+	}
+	var aliases []string
+	for _, typeName := range pkg.Aliases() {
+		aliases = append(aliases, typeName.Name())
+	}
+	if want := []string{"A", "S"}; !reflect.DeepEqual(aliases, want) {
+		t.Errorf("Aliases = %v, want %v", aliases, want)
+	}
+}
+
+func TestPackageObjectOf(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+var count = 1
+
+func Next() int {
+	count++
+	return count * 2
+}
+`})
+	var def, use *ast.Ident
+	var product ast.Expr
+	ast.Inspect(pkg.SrcFile("p.go").Syntax(), func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ValueSpec:
+			def = n.Names[0]
+		case *ast.ReturnStmt:
+			product = n.Results[0]
+			use = n.Results[0].(*ast.BinaryExpr).X.(*ast.Ident)
+		}
+		return true
+	})
+	if obj := pkg.ObjectOf(def); obj == nil || obj.Name() != "count" || pkg.ObjectOf(use) != obj {
+		t.Errorf("ObjectOf(use) = %v, want ObjectOf(def) = %v", pkg.ObjectOf(use), obj)
+	}
+	if typ := pkg.TypeOf(product); typ == nil || typ.String() != "int" {
+		t.Errorf("TypeOf(count * 2) = %v, want int", typ)
+	}
+	var unknown = ast.NewIdent("count")
+	if pkg.ObjectOf(unknown) != nil || pkg.TypeOf(unknown) != nil || pkg.ObjectOf(nil) != nil {
+		t.Errorf("unknown identifier is resolved")
+	}
+	var unchecked = newPackage(nil, "p", "example.com/p", "")
+	if unchecked.ObjectOf(def) != nil || unchecked.TypeOf(product) != nil {
+		t.Errorf("identifier is resolved without type checking")
+	}
+}
+
+func TestPackageMethodsOf(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type Inner struct{}
+
+func (Inner) Promoted() {}
+
+func (*Inner) PointerPromoted() {}
+
+type Outer struct{ Inner }
+
+func (Outer) Own() {}
+
+type Ptr struct{ *Inner }
+`})
+	for _, test := range []struct {
+		typeName string
+		methods  []string
+	}{
+		{"Outer", []string{"Own", "Promoted"}},
+		{"Ptr", []string{"PointerPromoted", "Promoted"}},
+		{"Undefined", nil},
+	} {
+		if got := funcNamesOf(pkg.MethodsOf(test.typeName)); !reflect.DeepEqual(got, test.methods) {
+			t.Errorf("MethodsOf(%s) = %v, want %v", test.typeName, got, test.methods)
+		}
+	}
+}
+
+func TestPackageImplements(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{"p/p.go": `package p
+
+import "io"
+
+type Reader struct{}
+
+func (*Reader) Read(p []byte) (int, error) { return 0, io.EOF }
+
+type Closer struct{}
+
+func (Closer) Close() error { return nil }
+
+type Named interface{ Name() string }
+
+type Empty struct{}
+`})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, testModulePath+"/p")
+	for _, test := range []struct {
+		typeName  string
+		ifaceName string
+		want      bool
+	}{
+		{"Reader", "io.Reader", true},
+		{"Closer", "io.Closer", true},
+		{"Closer", "io.Reader", false},
+		{"Empty", "Named", false},
+		{"Named", "Named", true},
+	} {
+		if got, err := pkg.Implements(test.typeName, test.ifaceName); err != nil || got != test.want {
+			t.Errorf("Implements(%s, %s) = %v, %v, want %v", test.typeName, test.ifaceName, got, err, test.want)
+		}
+	}
+	for _, test := range [][2]string{{"Undefined", "io.Reader"}, {"Reader", "Empty"}, {"Reader", "io.Undefined"}} {
+		if got, err := pkg.Implements(test[0], test[1]); err == nil {
+			t.Errorf("Implements(%s, %s) = %v, want error", test[0], test[1], got)
+		}
+	}
+}
+
+func TestVirtualPackageImplements(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "bufio"
+
+type T struct{ *bufio.Reader }
+`})
+	// io is resolved to the instance imported through bufio rather than a new one
+	for _, test := range []struct {
+		ifaceName string
+		want      bool
+	}{
+		{"io.WriterTo", true},
+		{"io.Reader", true},
+		{"io.Closer", false},
+	} {
+		if got, err := pkg.Implements("T", test.ifaceName); err != nil || got != test.want {
+			t.Errorf("Implements(T, %s) = %v, %v, want %v", test.ifaceName, got, err, test.want)
+		}
+	}
+}
+
+func TestPackageDeadCode(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type used struct{}
+
+type unusedType struct{}
+
+const unusedConst = 1
+
+var unusedVar = used{}
+
+func init() {}
+
+func helper() {}
+
+func unusedFunc() { helper() }
+
+func Exported() {}
+`})
+	var got []string
+	for _, symbol := range pkg.DeadCode() {
+		got = append(got, symbol.Reason)
+	}
+	var want = []string{
+		"unexported constant unusedConst is never used",
+		"unexported function unusedFunc is never used",
+		"unexported type unusedType is never used",
+		"unexported variable unusedVar is never used",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeadCode = %v, want %v", got, want)
+	}
+}
+
+func TestPackageUsedTypes(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"p.go": "package p\n\nimport \"strings\"\n\ntype T struct{ N int }\n\nfunc F(t *T) []byte {\n\t_ = t.N + 1\n\t_ = strings.Builder{}\n\treturn nil\n}\n",
+		"q.go": "package p\n\nfunc G(t T) int { return t.N }\n",
+	})
+	var want = []string{"*example.com/p.T", "[]byte", "byte", "example.com/p.T", "int", "strings.Builder", "struct{N int}", "untyped nil"}
+	if got := pkg.UsedTypeNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UsedTypeNames = %q, want %q", got, want)
+	}
+	usedTypes := pkg.UsedTypes()
+	if len(usedTypes) != len(want) {
+		t.Fatalf("UsedTypes = %v, want %d types", usedTypes, len(want))
+	}
+	if named, ok := usedTypes[3].(*types.Named); !ok || named.Obj() != pkg.TypePkg().Scope().Lookup("T") {
+		t.Errorf("UsedTypes[3] = %v, want the named type T", usedTypes[3])
+	}
+	if got := newPackage(nil, "p", "example.com/p", "").UsedTypes(); got != nil {
+		t.Errorf("UsedTypes of unchecked package = %v, want nil", got)
+	}
+}
+
+func TestPackageDocumentedExports(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\n// F does nothing.\nfunc F() {}\n\n// G is the doc of another.\nfunc Gx() {}\n\n" +
+			"// A Type is an integer.\ntype Type int\n\n// M is a method not counted.\nfunc (Type) M() {}\n",
+		"b.go": "package p\n\nconst (\n\t// C is a constant.\n\tC = 1\n\tD = 2\n)\n\n// V and W are variables.\nvar V, W int\n\n" +
+			"func H() {}\n\n// unexported is not counted.\nvar unexported int\n",
+	})
+	if documented, total, ratio := pkg.DocumentedExports(); documented != 4 || total != 8 || ratio != 0.5 {
+		t.Errorf("DocumentedExports = %d, %d, %v, want 4, 8, 0.5", documented, total, ratio)
+	}
+	empty := mustVirtualPackage(t, map[string]string{"a.go": "package p\n\nfunc f() {}\n"})
+	if documented, total, ratio := empty.DocumentedExports(); documented != 0 || total != 0 || ratio != 1 {
+		t.Errorf("DocumentedExports without exports = %d, %d, %v, want 0, 0, 1", documented, total, ratio)
+	}
+}
+
+func TestFunctionsReturningError(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type MyErr struct{}
+
+func (*MyErr) Error() string { return "" }
+
+type T struct{}
+
+func (T) Close() error { return nil }
+
+type U struct{}
+
+func (*U) Close() error { return nil }
+
+func Open() (int, error) { return 0, nil }
+
+func Parse() *MyErr { return nil }
+
+func Value() MyErr { return MyErr{} }
+
+func Plain() int {
+	var f = func() error { return nil }
+	_ = f
+	return 0
+}
+
+func open() error { return nil }
+`})
+	var names []string
+	for _, function := range pkg.FunctionsReturningError() {
+		names = append(names, function.FullName())
+	}
+	var want = []string{"(*example.com/p.U).Close", "(example.com/p.T).Close",
+		"example.com/p.Open", "example.com/p.Parse", "example.com/p.open"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("FunctionsReturningError = %q, want %q", names, want)
+	}
+	if got := newPackage(nil, "p", "example.com/p", "").FunctionsReturningError(); got != nil {
+		t.Errorf("FunctionsReturningError of unchecked package = %v, want nil", got)
+	}
+}
+
+func TestSrcFileFuncSignatures(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": `package p
+
+import "io"
+
+type Package struct{}
+
+type SrcFile struct{}
+
+func (pkg *Package) NewSrcFile(srcPath string) *SrcFile { return nil }
+
+func (Package) Name() string { return "" }
+
+func Copy(w io.Writer, r io.Reader, n ...int) (int64, error) { return 0, nil }
+
+func Map[T any](x T) T { return x }
+`,
+		"b.go": "package p\n\nfunc Other() {}\n",
+	})
+	var want = map[string]string{
+		"(*Package).NewSrcFile": "func (pkg *Package) NewSrcFile(srcPath string) *SrcFile",
+		"Package.Name":          "func (Package) Name() string",
+		"Copy":                  "func Copy(w io.Writer, r io.Reader, n ...int) (int64, error)",
+		"Map":                   "func Map[T any](x T) T",
+	}
+	if got := pkg.SrcFile("a.go").FuncSignatures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FuncSignatures = %v, want %v", got, want)
+	}
+	if got := pkg.SrcFile("b.go").FuncSignatures(); !reflect.DeepEqual(got, map[string]string{"Other": "func Other()"}) {
+		t.Errorf("FuncSignatures of b.go = %v, want only Other", got)
+	}
+	if got := newSrcFile(nil, "a.go").FuncSignatures(); got != nil {
+		t.Errorf("FuncSignatures without type info = %v, want nil", got)
+	}
+}
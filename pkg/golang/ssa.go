@@ -0,0 +1,220 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the building of static single assignment (SSA) form for the
+// packages, of which the members are distributed to the source files where they are declared.
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// ssaBuilderMode is the mode used to build SSA form of the packages, which builds the functions in
+// the calling goroutine, such that the panics in building could be recovered as errors.
+const ssaBuilderMode = ssa.BuildSerially
+
+// createImportedSSA creates the SSA packages (without syntax) of the imported packages recursively.
+func createImportedSSA(ssaProg *ssa.Program, imports []*types.Package, created map[*types.Package]bool) {
+	for _, typePkg := range imports {
+		if typePkg != nil && !created[typePkg] {
+			created[typePkg] = true
+			ssaProg.CreatePackage(typePkg, nil, nil, true)
+			createImportedSSA(ssaProg, typePkg.Imports(), created)
+		}
+	}
+}
+
+// BuildSSA builds the SSA form of this package from its types.Package and types.Info, and updates
+// the members of each source file with those declared in it. The package must be well-typed.
+func (pkg *Package) BuildSSA() (err error) {
+	// 1. validate the type information of package
+	if pkg == nil {
+		return fmt.Errorf("nil package is used")
+	}
+	if pkg.typePkg == nil || pkg.typInfo == nil || pkg.fileSet == nil {
+		return fmt.Errorf("package not type-checked: %s", pkg.pkgPath)
+	}
+	if pkg.typInfo.Selections == nil {
+		return fmt.Errorf("package loaded with minimal type info: %s", pkg.pkgPath)
+	}
+	if pkg.loadInfo != nil && pkg.loadInfo.IllTyped {
+		return fmt.Errorf("package is ill-typed: %s", pkg.pkgPath)
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("can't build SSA of %s: %v", pkg.pkgPath, e)
+		}
+	}()
+
+	// 2. create SSA program with the imported packages
+	ssaProg := ssa.NewProgram(pkg.fileSet, ssaBuilderMode)
+	createImportedSSA(ssaProg, pkg.typePkg.Imports(), make(map[*types.Package]bool))
+
+	// 3. create and build the SSA package from syntax
+	srcFiles := pkg.syntaxFiles()
+	astFiles := make([]*ast.File, 0, len(srcFiles))
+	for _, srcFile := range srcFiles {
+		astFiles = append(astFiles, srcFile.syntax)
+	}
+	ssaPkg := ssaProg.CreatePackage(pkg.typePkg, astFiles, pkg.typInfo, false)
+	ssaPkg.Build()
+	pkg.ssaPkg, pkg.ssaFuncs = ssaPkg, nil
+
+	// 4. distribute the SSA members to source files
+	for _, srcFile := range srcFiles {
+		if fileErr := srcFile.update(srcFile.code, srcFile.syntax, ssaPkg.Members); fileErr != nil {
+			return fileErr
+		}
+	}
+	return nil
+}
+
+// SSAPkg returns the SSA form of this package, or nil if it is not built yet.
+func (pkg *Package) SSAPkg() *ssa.Package {
+	if pkg != nil {
+		return pkg.ssaPkg
+	}
+	return nil
+}
+
+// parseFuncName splits the function name as "Foo", "T.Foo", "(T).Foo" or "(*T).Foo" to the name of
+// receiver type (empty for function), the method (or function) name and if receiver is a pointer.
+func parseFuncName(funcName string) (typeName, methodName string, isPointer bool) {
+	var dot = strings.LastIndex(funcName, ".")
+	if dot < 0 {
+		return "", funcName, false
+	}
+	typeName, methodName = funcName[:dot], funcName[dot+1:]
+	if strings.HasPrefix(typeName, "(") && strings.HasSuffix(typeName, ")") {
+		typeName = typeName[1 : len(typeName)-1]
+	}
+	if strings.HasPrefix(typeName, "*") {
+		typeName, isPointer = typeName[1:], true
+	}
+	return strings.TrimSpace(typeName), methodName, isPointer
+}
+
+// SSAFunc returns the SSA function declared in this package by name, which is either the name of a
+// package-level function (e.g. "Foo") or a method with its receiver (e.g. "(*T).Foo" or "T.Foo").
+//
+// The SSA form of package is built on demand in the first call if not built yet, since x/tools can
+// only build SSA functions of a package as a whole, and the functions found are cached by names.
+func (pkg *Package) SSAFunc(funcName string) (*ssa.Function, error) {
+	// 1. build the SSA package on demand and find cache
+	if pkg == nil {
+		return nil, fmt.Errorf("nil package is used")
+	}
+	if fn, ok := pkg.ssaFuncs[funcName]; ok {
+		return fn, nil
+	}
+	if pkg.ssaPkg == nil {
+		if buildErr := pkg.BuildSSA(); buildErr != nil {
+			return nil, buildErr
+		}
+	}
+
+	// 2. find the SSA function or method by its name
+	var fn *ssa.Function
+	typeName, methodName, isPointer := parseFuncName(funcName)
+	if len(typeName) == 0 {
+		fn = pkg.ssaPkg.Func(methodName)
+	} else if named := pkg.namedTypeOf(typeName); named != nil {
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+			if method.Name() != methodName {
+				continue
+			}
+			signature, _ := method.Type().(*types.Signature)
+			if signature != nil && signature.Recv() != nil {
+				_, isPointerRecv := signature.Recv().Type().(*types.Pointer)
+				if isPointerRecv != isPointer {
+					return nil, fmt.Errorf("receiver mismatched: %s", funcName)
+				}
+			}
+			fn = pkg.ssaPkg.Prog.FuncValue(method)
+			break
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("function not found: %s", funcName)
+	}
+
+	// 3. cache the SSA function for the later queries
+	if pkg.ssaFuncs == nil {
+		pkg.ssaFuncs = make(map[string]*ssa.Function)
+	}
+	pkg.ssaFuncs[funcName] = fn
+	return fn, nil
+}
+
+// BuildSSA builds the SSA form of the whole program sharing the program's FileSet, which contains
+// the well-typed packages in program and their imported ones, and updates the members of source
+// files in each package. The ill-typed packages are created without the syntax (function bodies).
+//
+// Unlike building the packages separately, the program-wide SSA links the functions across their
+// packages, which is required by interprocedural analysis such as call graph.
+func (prog *Program) BuildSSA() (ssaProg *ssa.Program, err error) {
+	// 1. validate the program and its packages
+	if prog == nil {
+		return nil, fmt.Errorf("nil program is used")
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			ssaProg, err = nil, fmt.Errorf("can't build SSA of program: %v", e)
+		}
+	}()
+	var pkgs []*Package
+	for _, pkg := range prog.AllPackages() {
+		if pkg.typePkg != nil && pkg.typInfo != nil && pkg.typInfo.Selections != nil &&
+			pkg.loadInfo != nil && !pkg.loadInfo.IllTyped {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].pkgPath < pkgs[j].pkgPath })
+
+	// 2. create the SSA packages with syntax in program
+	ssaProg = ssa.NewProgram(prog.fileSet, ssaBuilderMode)
+	var created = make(map[*types.Package]bool)
+	for _, pkg := range pkgs {
+		created[pkg.typePkg] = true
+	}
+	for _, pkg := range pkgs {
+		var astFiles []*ast.File
+		for _, srcFile := range pkg.syntaxFiles() {
+			astFiles = append(astFiles, srcFile.syntax)
+		}
+		pkg.ssaPkg = ssaProg.CreatePackage(pkg.typePkg, astFiles, pkg.typInfo, true)
+		pkg.ssaFuncs = nil
+	}
+
+	// 3. create the imported packages and build them
+	for _, pkg := range pkgs {
+		createImportedSSA(ssaProg, pkg.typePkg.Imports(), created)
+	}
+	ssaProg.Build()
+	prog.ssaProg = ssaProg
+
+	// 4. distribute the SSA members to source files
+	for _, pkg := range pkgs {
+		for _, srcFile := range pkg.syntaxFiles() {
+			if fileErr := srcFile.update(srcFile.code, srcFile.syntax, pkg.ssaPkg.Members); fileErr != nil {
+				return nil, fileErr
+			}
+		}
+	}
+	return ssaProg, nil
+}
+
+// SSAProgram returns the SSA form of the whole program, or nil if it is not built yet.
+func (prog *Program) SSAProgram() *ssa.Program {
+	if prog != nil {
+		return prog.ssaProg
+	}
+	return nil
+}
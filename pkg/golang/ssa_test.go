@@ -0,0 +1,147 @@
+package golang
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// memberNamesOf returns the sorted names of the SSA functions in the members.
+func memberNamesOf(members []ssa.Member) []string {
+	var names []string
+	for _, member := range members {
+		if _, ok := member.(*ssa.Function); ok {
+			names = append(names, member.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPackageBuildSSA(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"p/a.go": "package p\n\nfunc A() int { return B() + 1 }\n\nvar X = 1\n",
+		"p/b.go": "package p\n\nfunc B() int { return 2 }\n\nfunc C() {}\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := mustPackage(t, prog, testModulePath+"/p")
+	var aFile = pkg.SrcFile(filepath.Join(rootDir, "p", "a.go"))
+	var bFile = pkg.SrcFile(filepath.Join(rootDir, "p", "b.go"))
+	if aFile.Members() != nil || pkg.SSAPkg() != nil {
+		t.Fatalf("SSA is built before BuildSSA")
+	}
+	if err := pkg.BuildSSA(); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.SSAPkg() == nil || pkg.SSAPkg().Func("A") == nil {
+		t.Fatalf("SSAPkg = %v, want the package with A", pkg.SSAPkg())
+	}
+	if got := memberNamesOf(aFile.Members()); !reflect.DeepEqual(got, []string{"A"}) {
+		t.Errorf("members of a.go = %v, want [A]", got)
+	}
+	if got := memberNamesOf(bFile.Members()); !reflect.DeepEqual(got, []string{"B", "C"}) {
+		t.Errorf("members of b.go = %v, want [B C]", got)
+	}
+	for _, member := range aFile.Members() {
+		if !aFile.Contain(member.Pos()) {
+			t.Errorf("member %s is not declared in a.go", member.Name())
+		}
+	}
+
+	var illTyped = newPackage(nil, "p", "example.com/p", "")
+	if err := illTyped.BuildSSA(); err == nil {
+		t.Errorf("BuildSSA(not type-checked) = nil, want error")
+	}
+	virtual, err := NewVirtualPackage("p", "example.com/p", map[string]string{"p.go": "package p\n\nvar X = undefined\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := virtual.BuildSSA(); err == nil {
+		t.Errorf("BuildSSA(ill-typed) = nil, want error")
+	}
+}
+
+func TestProgramBuildSSA(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\nfunc A() int { return b.B() }\n",
+		"b/b.go": "package b\n\nfunc B() int { return 1 }\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssaProg, err := prog.BuildSSA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.SSAProgram() != ssaProg {
+		t.Errorf("SSAProgram = %v, want the one built", prog.SSAProgram())
+	}
+	var a, b = mustPackage(t, prog, testModulePath+"/a"), mustPackage(t, prog, testModulePath+"/b")
+	var aFunc, bFunc = a.SSAPkg().Func("A"), b.SSAPkg().Func("B")
+	if aFunc == nil || bFunc == nil || aFunc.Prog != ssaProg || bFunc.Prog != ssaProg {
+		t.Fatalf("A and B are not in the program: %v, %v", aFunc, bFunc)
+	}
+	if ssaProg.Package(a.TypePkg()) != a.SSAPkg() || ssaProg.Package(b.TypePkg()) != b.SSAPkg() {
+		t.Errorf("packages are not shared in the program")
+	}
+
+	// the call in A refers to the function B in the same program
+	var callee *ssa.Function
+	for _, block := range aFunc.Blocks {
+		for _, instr := range block.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				callee = call.Call.StaticCallee()
+			}
+		}
+	}
+	if callee != bFunc {
+		t.Errorf("A calls %v, want %v", callee, bFunc)
+	}
+	if got := memberNamesOf(b.SrcFile(filepath.Join(rootDir, "b", "b.go")).Members()); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("members of b.go = %v, want [B]", got)
+	}
+}
+
+func TestPackageSSAFunc(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type T struct{ n int }
+
+func (t *T) Foo() int { return t.n }
+
+func (t T) Bar() int { return t.n }
+
+func Top() int { return new(T).Foo() }
+`})
+	for _, name := range []string{"Top", "(*T).Foo", "T.Bar", "(T).Bar"} {
+		fn, err := pkg.SSAFunc(name)
+		if err != nil {
+			t.Errorf("SSAFunc(%q): %v", name, err)
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			t.Errorf("SSAFunc(%q) = %v without body", name, fn)
+		}
+		if cached, _ := pkg.SSAFunc(name); cached != fn {
+			t.Errorf("SSAFunc(%q) = %v, want the cached %v", name, cached, fn)
+		}
+	}
+	if pkg.SSAPkg() == nil {
+		t.Errorf("SSA package is not built on demand")
+	}
+	if fn, _ := pkg.SSAFunc("(*T).Foo"); fn.Signature.Recv() == nil || fn.Name() != "Foo" {
+		t.Errorf("SSAFunc((*T).Foo) = %v, want the method", fn)
+	}
+	for _, name := range []string{"Undefined", "T.Foo", "(*T).Undefined", "U.Foo"} {
+		if fn, err := pkg.SSAFunc(name); err == nil {
+			t.Errorf("SSAFunc(%q) = %v, want error", name, fn)
+		}
+	}
+}
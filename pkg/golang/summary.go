@@ -0,0 +1,50 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the summaries of Package and Program, which snapshot the state
+// of loading into machine-readable reports (in JSON) consumed by dashboards.
+package golang
+
+import (
+	"sort"
+)
+
+// PackageSummary is the summary of a Package's loading state, which is encoded in JSON with the
+// stable field names in tags.
+type PackageSummary struct {
+	PkgName  string   `json:"pkgName"`  // PkgName is the short name to refer this package
+	PkgPath  string   `json:"pkgPath"`  // PkgPath is logical path to import this package
+	DirPath  string   `json:"dirPath"`  // DirPath is the absolute path of package directory
+	NumFiles int      `json:"numFiles"` // NumFiles is the number of source files in the package
+	Imports  []string `json:"imports"`  // Imports are the sorted paths of packages imported
+	IsLoaded bool     `json:"isLoaded"` // IsLoaded is true if the package has been loaded
+	IllTyped bool     `json:"illTyped"` // IllTyped is true if any type error occurs in loading
+}
+
+// Summary returns the summary of this package's loading state.
+func (pkg *Package) Summary() PackageSummary {
+	if pkg == nil {
+		return PackageSummary{}
+	}
+	var imports = append([]string{}, pkg.imports...)
+	sort.Strings(imports)
+	return PackageSummary{
+		PkgName:  pkg.pkgName,
+		PkgPath:  pkg.pkgPath,
+		DirPath:  pkg.dirPath,
+		NumFiles: len(pkg.GoFiles()),
+		Imports:  imports,
+		IsLoaded: pkg.IsLoaded(),
+		IllTyped: pkg.loadInfo != nil && pkg.loadInfo.IllTyped,
+	}
+}
+
+// Summaries returns the summaries of all packages in the program sorted by their paths.
+func (prog *Program) Summaries() []PackageSummary {
+	var summaries []PackageSummary
+	for _, pkg := range prog.AllPackages() {
+		summaries = append(summaries, pkg.Summary())
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].PkgPath < summaries[j].PkgPath })
+	return summaries
+}
@@ -0,0 +1,51 @@
+package golang
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestProgramSummaries(t *testing.T) {
+	rootDir := writeModule(t, map[string]string{
+		"b/b.go": "package b\n\nimport (\n\t\"strings\"\n\n\t\"example.com/m/a\"\n)\n\nvar B = strings.ToUpper(a.A)\n",
+		"a/a.go": "package a\n\nvar A = \"a\"\n",
+		"c/c.go": "package c\n\nvar C = undefined\n",
+	})
+	prog, err := Load(rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries := prog.Summaries()
+	var pkgPaths []string
+	for _, summary := range summaries {
+		pkgPaths = append(pkgPaths, summary.PkgPath)
+	}
+	if want := []string{testModulePath + "/a", testModulePath + "/b", testModulePath + "/c"}; !reflect.DeepEqual(pkgPaths, want) {
+		t.Fatalf("paths of Summaries = %v, want %v", pkgPaths, want)
+	}
+	if c := summaries[2]; !c.IsLoaded || !c.IllTyped || c.NumFiles != 1 || c.PkgName != "c" {
+		t.Errorf("summary of c = %+v", c)
+	}
+
+	data, err := json.Marshal(summaries[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var want = map[string]any{
+		"pkgName":  "b",
+		"pkgPath":  testModulePath + "/b",
+		"dirPath":  summaries[1].DirPath,
+		"numFiles": float64(1),
+		"imports":  []any{testModulePath + "/a", "strings"},
+		"isLoaded": true,
+		"illTyped": false,
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("JSON of b = %s, want %v", data, want)
+	}
+}
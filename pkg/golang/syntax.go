@@ -0,0 +1,542 @@
+// Package golang implements the model to load and represent syntax and semantic information from
+// source code in the .go files.
+//
+// Specifically, this file implements the queries on the abstract syntax tree of SrcFile, such as
+// its imports and declarations, which are commonly used by the analyzers of single source file.
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ImportSpec is an import declared in the source file, along with its local name.
+type ImportSpec struct {
+	Path  string // Path is the logical path of the imported package
+	Alias string // Alias is the local name declared in the import, or empty if none
+	Dot   bool   // Dot is true if the package is imported into the file block as `. "path"`
+	Blank bool   // Blank is true if the package is imported only for side effects as `_ "path"`
+}
+
+// Imports returns the packages imported in this source file, in the order of import declarations,
+// or nil if its syntax tree is not loaded.
+func (file *SrcFile) Imports() []ImportSpec {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var imports []ImportSpec
+	for _, importSpec := range file.syntax.Imports {
+		if importSpec == nil || importSpec.Path == nil {
+			continue
+		}
+		var spec = ImportSpec{Path: strings.Trim(importSpec.Path.Value, "\"")}
+		if importSpec.Name != nil {
+			spec.Alias = importSpec.Name.Name
+			spec.Dot = spec.Alias == "."
+			spec.Blank = spec.Alias == "_"
+		}
+		imports = append(imports, spec)
+	}
+	return imports
+}
+
+// ImportAlias returns the local name of the package imported in the path by this source file, i.e.
+// the alias if declared in the import, or otherwise the last element of import path (as the default
+// package name). It returns empty if the path is not imported in this file.
+func (file *SrcFile) ImportAlias(importPath string) string {
+	if file == nil || file.syntax == nil {
+		return ""
+	}
+	for _, importSpec := range file.syntax.Imports {
+		if importSpec == nil || importSpec.Path == nil {
+			continue
+		}
+		if strings.Trim(importSpec.Path.Value, "\"") != importPath {
+			continue
+		}
+		if importSpec.Name != nil {
+			return importSpec.Name.Name
+		}
+		return path.Base(importPath)
+	}
+	return ""
+}
+
+// DocComment returns the text of the doc comment attached above the package clause of this source
+// file, or empty if there is none or its syntax tree is not loaded.
+func (file *SrcFile) DocComment() string {
+	if file == nil || file.syntax == nil || file.syntax.Doc == nil {
+		return ""
+	}
+	return file.syntax.Doc.Text()
+}
+
+// Doc returns the package documentation, i.e. the doc comment of the package clause in its source
+// files, preferring the conventional doc.go and then the first file (by path) that carries one.
+func (pkg *Package) Doc() string {
+	if pkg == nil {
+		return ""
+	}
+	var srcFiles = pkg.syntaxFiles()
+	for _, srcFile := range srcFiles {
+		if filepath.Base(srcFile.path) == "doc"+GoFileSuffix && len(srcFile.DocComment()) > 0 {
+			return srcFile.DocComment()
+		}
+	}
+	for _, srcFile := range srcFiles {
+		if doc := srcFile.DocComment(); len(doc) > 0 {
+			return doc
+		}
+	}
+	return ""
+}
+
+// AnnotationKinds are the kinds of annotations recognized by SrcFile.Annotations by default.
+var AnnotationKinds = []string{"Deprecated", "TODO", "FIXME", "BUG", "HACK", "XXX", "NOTE"}
+
+// Annotation is a line in comments written as `// Kind: text`, such as `// Deprecated: use Bar`.
+type Annotation struct {
+	Kind string         // Kind is the kind of annotation, e.g. "Deprecated" or "TODO"
+	Text string         // Text is the trailing text after the colon, with spaces trimmed
+	Pos  token.Position // Pos is the position of the annotation kind in the source file
+}
+
+// Annotations returns the annotations of the kinds in AnnotationKinds in the comments of this file.
+func (file *SrcFile) Annotations() []Annotation {
+	return file.AnnotationsOf(AnnotationKinds...)
+}
+
+// AnnotationsOf returns the annotations of the given kinds in comments of this source file in order
+// of positions, which are the lines starting with `Kind:` in either line or block comments, or nil
+// if its syntax tree is not loaded.
+func (file *SrcFile) AnnotationsOf(kinds ...string) []Annotation {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return nil
+	}
+	var annotations []Annotation
+	for _, group := range file.syntax.Comments {
+		for _, comment := range group.List {
+			var offset = 0
+			for _, line := range strings.SplitAfter(comment.Text, NewLine) {
+				lineOffset := offset
+				offset += len(line)
+				if lineOffset == 0 {
+					line = strings.TrimPrefix(strings.TrimPrefix(line, "//"), "/*")
+					lineOffset = 2
+				} else {
+					line = strings.TrimLeft(line, " \t*")
+					lineOffset = offset - len(line)
+				}
+				var trimmed = strings.TrimLeft(line, " \t")
+				lineOffset += len(line) - len(trimmed)
+				for _, kind := range kinds {
+					if !strings.HasPrefix(trimmed, kind+":") {
+						continue
+					}
+					text := strings.TrimSuffix(strings.TrimSpace(trimmed[len(kind)+1:]), "*/")
+					annotations = append(annotations, Annotation{
+						Kind: kind,
+						Text: strings.TrimSpace(text),
+						Pos:  file.pkg.fileSet.Position(comment.Pos() + token.Pos(lineOffset)),
+					})
+					break
+				}
+			}
+		}
+	}
+	return annotations
+}
+
+// NodeAt returns the smallest node in the syntax tree of this source file whose range contains the
+// position, or nil if the position is out of this file (in the FileSet of package).
+func (file *SrcFile) NodeAt(pos token.Pos) ast.Node {
+	if file == nil || file.syntax == nil || file.pkg == nil || file.pkg.fileSet == nil {
+		return nil
+	}
+	if !file.Contain(pos) {
+		return nil
+	}
+	var found ast.Node
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		if node == nil || pos < node.Pos() || pos >= node.End() {
+			return false
+		}
+		found = node // the children are visited after their parent
+		return true
+	})
+	return found
+}
+
+// Functions returns the top-level declarations of functions and methods in this source file in the
+// order they appear in source, excluding the function literals, or nil if syntax isn't loaded.
+func (file *SrcFile) Functions() []*ast.FuncDecl {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var functions []*ast.FuncDecl
+	for _, decl := range file.syntax.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			functions = append(functions, funcDecl)
+		}
+	}
+	return functions
+}
+
+// inspectWithStack traverses the syntax tree in depth-first order like ast.Inspect, and calls the
+// visit function with each node along with the stack of its ancestors (ending with the node).
+func inspectWithStack(root ast.Node, visit func(node ast.Node, stack []ast.Node) bool) {
+	var stack []ast.Node
+	ast.Inspect(root, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+		if !visit(node, stack) {
+			stack = stack[:len(stack)-1] // children and the closing nil are skipped
+			return false
+		}
+		return true
+	})
+}
+
+// inLoop checks whether the innermost node in the stack is enclosed by a loop in the same function.
+func inLoop(stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		case *ast.FuncLit:
+			return false
+		}
+	}
+	return false
+}
+
+// DeferStatements returns the defer statements in this source file, or nil if syntax isn't loaded.
+func (file *SrcFile) DeferStatements() []*ast.DeferStmt {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var defers []*ast.DeferStmt
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		if deferStmt, ok := node.(*ast.DeferStmt); ok {
+			defers = append(defers, deferStmt)
+		}
+		return true
+	})
+	return defers
+}
+
+// DefersInsideLoops returns the defer statements enclosed by a for or range loop in the same function,
+// which are not executed until the function returns and thus accumulate through the iterations.
+func (file *SrcFile) DefersInsideLoops() []*ast.DeferStmt {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var defers []*ast.DeferStmt
+	inspectWithStack(file.syntax, func(node ast.Node, stack []ast.Node) bool {
+		if deferStmt, ok := node.(*ast.DeferStmt); ok && inLoop(stack) {
+			defers = append(defers, deferStmt)
+		}
+		return true
+	})
+	return defers
+}
+
+// SelectStatements returns the select statements in this source file, or nil if syntax isn't loaded.
+func (file *SrcFile) SelectStatements() []*ast.SelectStmt {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var selects []*ast.SelectStmt
+	ast.Inspect(file.syntax, func(node ast.Node) bool {
+		if selectStmt, ok := node.(*ast.SelectStmt); ok {
+			selects = append(selects, selectStmt)
+		}
+		return true
+	})
+	return selects
+}
+
+// selectCasesOf returns the number of communication cases in the select statement, and whether it
+// has a default case.
+func selectCasesOf(selectStmt *ast.SelectStmt) (cases int, hasDefault bool) {
+	for _, stmt := range selectStmt.Body.List {
+		if commClause, ok := stmt.(*ast.CommClause); ok {
+			if commClause.Comm == nil {
+				hasDefault = true
+			} else {
+				cases++
+			}
+		}
+	}
+	return cases, hasDefault
+}
+
+// BlockingSelects returns the select statements without default case in this source file, which
+// block until any case is ready and might deadlock if no channel ever is (e.g. `select {}`).
+func (file *SrcFile) BlockingSelects() []*ast.SelectStmt {
+	var selects []*ast.SelectStmt
+	for _, selectStmt := range file.SelectStatements() {
+		if _, hasDefault := selectCasesOf(selectStmt); !hasDefault {
+			selects = append(selects, selectStmt)
+		}
+	}
+	return selects
+}
+
+// SingleCaseSelects returns the select statements in source files of this package with exactly one
+// communication case and no default, which could be replaced with the send or receive directly.
+func (pkg *Package) SingleCaseSelects() []*ast.SelectStmt {
+	if pkg == nil {
+		return nil
+	}
+	var selects []*ast.SelectStmt
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, selectStmt := range srcFile.BlockingSelects() {
+			if cases, _ := selectCasesOf(selectStmt); cases == 1 {
+				selects = append(selects, selectStmt)
+			}
+		}
+	}
+	return selects
+}
+
+// hasNamedResults checks whether any result of the function type is named other than blank.
+func hasNamedResults(funcType *ast.FuncType) bool {
+	if funcType == nil || funcType.Results == nil {
+		return false
+	}
+	for _, field := range funcType.Results.List {
+		for _, name := range field.Names {
+			if name != nil && name.Name != "_" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NamedReturns returns the top-level functions and methods in this source file, of which at least
+// one result is named (other than blank), or nil if syntax isn't loaded.
+func (file *SrcFile) NamedReturns() []*ast.FuncDecl {
+	var functions []*ast.FuncDecl
+	for _, funcDecl := range file.Functions() {
+		if hasNamedResults(funcDecl.Type) {
+			functions = append(functions, funcDecl)
+		}
+	}
+	return functions
+}
+
+// NakedReturns returns the bare return statements (without results) in the functions with named
+// results in this source file, excluding those in the function literals which return by their own.
+func (file *SrcFile) NakedReturns() []*ast.ReturnStmt {
+	var returns []*ast.ReturnStmt
+	for _, funcDecl := range file.NamedReturns() {
+		if funcDecl.Body == nil {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			switch stmt := node.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				if len(stmt.Results) == 0 {
+					returns = append(returns, stmt)
+				}
+			}
+			return true
+		})
+	}
+	return returns
+}
+
+// isTestFuncName checks whether the name is recognized by `go test` as the function of the kind in
+// prefix (e.g. "Benchmark" or "Example"), i.e. the prefix is not followed by a lower-case letter.
+func isTestFuncName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(next)
+}
+
+// testFunctionsOf returns the top-level functions (not methods) in the test files of package, which
+// are named with the prefix and matched by the function, in the order of files and declarations.
+func (pkg *Package) testFunctionsOf(prefix string, match func(*ast.FuncDecl) bool) []*ast.FuncDecl {
+	if pkg == nil {
+		return nil
+	}
+	var functions []*ast.FuncDecl
+	for _, srcFile := range pkg.syntaxFiles() {
+		if !srcFile.IsTest() {
+			continue
+		}
+		for _, funcDecl := range srcFile.Functions() {
+			if funcDecl.Recv == nil && isTestFuncName(funcDecl.Name.Name, prefix) && match(funcDecl) {
+				functions = append(functions, funcDecl)
+			}
+		}
+	}
+	return functions
+}
+
+// Benchmarks returns the benchmark functions in the test files of package, which are named with the
+// prefix "Benchmark" and take the only parameter of *testing.B.
+func (pkg *Package) Benchmarks() []*ast.FuncDecl {
+	return pkg.testFunctionsOf("Benchmark", func(funcDecl *ast.FuncDecl) bool {
+		params := funcDecl.Type.Params
+		if params == nil || len(params.List) != 1 || len(params.List[0].Names) > 1 {
+			return false
+		}
+		if typ := pkg.TypeOf(params.List[0].Type); typ != nil {
+			pointer, ok := typ.(*types.Pointer)
+			return ok && isNamedTypeOf(pointer.Elem(), "testing", "B")
+		}
+		return types.ExprString(params.List[0].Type) == "*testing.B" // type info isn't loaded
+	})
+}
+
+// ExampleFunctions returns the example functions in the test files of package, which are named with
+// the prefix "Example" and take no parameter or result.
+func (pkg *Package) ExampleFunctions() []*ast.FuncDecl {
+	return pkg.testFunctionsOf("Example", func(funcDecl *ast.FuncDecl) bool {
+		return funcDecl.Type.Params.NumFields() == 0 && funcDecl.Type.Results.NumFields() == 0
+	})
+}
+
+// InitFunctions returns the init functions declared in this source file, i.e. the top-level functions
+// named "init" without receiver, parameter or result, in the order of declarations.
+func (file *SrcFile) InitFunctions() []*ast.FuncDecl {
+	var functions []*ast.FuncDecl
+	for _, funcDecl := range file.Functions() {
+		if funcDecl.Recv == nil && funcDecl.Name.Name == "init" &&
+			funcDecl.Type.Params.NumFields() == 0 && funcDecl.Type.Results.NumFields() == 0 {
+			functions = append(functions, funcDecl)
+		}
+	}
+	return functions
+}
+
+// GenericFunctions returns the declarations of generic functions in source files of this package,
+// i.e. those with type parameters, in order of files and their declarations.
+func (pkg *Package) GenericFunctions() []*ast.FuncDecl {
+	if pkg == nil {
+		return nil
+	}
+	var functions []*ast.FuncDecl
+	for _, srcFile := range pkg.syntaxFiles() {
+		for _, funcDecl := range srcFile.Functions() {
+			if funcDecl.Type.TypeParams != nil && funcDecl.Type.TypeParams.NumFields() > 0 {
+				functions = append(functions, funcDecl)
+			}
+		}
+	}
+	return functions
+}
+
+// TypeParameters maps the names of generic functions in this package to their type parameters.
+func (pkg *Package) TypeParameters() map[string][]*ast.Field {
+	var typeParams = make(map[string][]*ast.Field)
+	for _, funcDecl := range pkg.GenericFunctions() {
+		typeParams[funcDecl.Name.Name] = funcDecl.Type.TypeParams.List
+	}
+	return typeParams
+}
+
+// InstantiatedGenerics returns the instances of generic functions and types used in this source
+// file in order of their positions, or nil if the package is not type-checked.
+func (file *SrcFile) InstantiatedGenerics() []*types.Instance {
+	if file == nil || file.pkg == nil || file.pkg.typInfo == nil || file.syntax == nil {
+		return nil
+	}
+	var idents []*ast.Ident
+	for ident := range file.pkg.typInfo.Instances {
+		if ident.Pos() >= file.syntax.Pos() && ident.Pos() < file.syntax.End() {
+			idents = append(idents, ident)
+		}
+	}
+	sort.Slice(idents, func(i, j int) bool { return idents[i].Pos() < idents[j].Pos() })
+	var instances = make([]*types.Instance, 0, len(idents))
+	for _, ident := range idents {
+		instance := file.pkg.typInfo.Instances[ident]
+		instances = append(instances, &instance)
+	}
+	return instances
+}
+
+// InitFunctions maps the absolute paths of source files in this package to the init functions in
+// them, excluding the files without any init function.
+func (pkg *Package) InitFunctions() map[string][]*ast.FuncDecl {
+	if pkg == nil {
+		return nil
+	}
+	var functions = make(map[string][]*ast.FuncDecl)
+	for _, srcFile := range pkg.syntaxFiles() {
+		if initFunctions := srcFile.InitFunctions(); len(initFunctions) > 0 {
+			functions[srcFile.path] = initFunctions
+		}
+	}
+	return functions
+}
+
+// InitOrder returns the package-level variables in the order they are initialized, along with their
+// initialization expressions, as resolved in type checking. It returns nil if type info isn't loaded.
+func (pkg *Package) InitOrder() []*types.Initializer {
+	if pkg == nil || pkg.typInfo == nil {
+		return nil
+	}
+	return pkg.typInfo.InitOrder
+}
+
+// valueSpecsOf returns the value specs declared by the top-level GenDecl with the token (VAR or
+// CONST) in this source file, skipping those declaring only the blank identifiers.
+func (file *SrcFile) valueSpecsOf(tok token.Token) []*ast.ValueSpec {
+	if file == nil || file.syntax == nil {
+		return nil
+	}
+	var valueSpecs []*ast.ValueSpec
+	for _, decl := range file.syntax.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != tok {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for _, name := range valueSpec.Names {
+				if name.Name != "_" {
+					valueSpecs = append(valueSpecs, valueSpec)
+					break
+				}
+			}
+		}
+	}
+	return valueSpecs
+}
+
+// GlobalVars returns the specs of package-level variables declared in this source file, skipping
+// those of blank identifiers, or nil if syntax isn't loaded.
+func (file *SrcFile) GlobalVars() []*ast.ValueSpec {
+	return file.valueSpecsOf(token.VAR)
+}
+
+// GlobalConsts returns the specs of package-level constants declared in this source file, skipping
+// those of blank identifiers, or nil if syntax isn't loaded.
+func (file *SrcFile) GlobalConsts() []*ast.ValueSpec {
+	return file.valueSpecsOf(token.CONST)
+}
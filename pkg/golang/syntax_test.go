@@ -0,0 +1,448 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSrcFileImports(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import (
+	"fmt"
+	str "strings"
+	. "math"
+	_ "embed"
+	"math/rand"
+)
+
+var _ = fmt.Sprint(str.ToUpper("a"), Pi, rand.Int())
+`})
+	srcFile := pkg.SrcFile("p.go")
+	var want = []ImportSpec{
+		{Path: "fmt"},
+		{Path: "strings", Alias: "str"},
+		{Path: "math", Alias: ".", Dot: true},
+		{Path: "embed", Alias: "_", Blank: true},
+		{Path: "math/rand"},
+	}
+	if got := srcFile.Imports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Imports = %+v, want %+v", got, want)
+	}
+	for importPath, alias := range map[string]string{
+		"fmt":       "fmt",
+		"strings":   "str",
+		"math":      ".",
+		"embed":     "_",
+		"math/rand": "rand",
+		"os":        "",
+	} {
+		if got := srcFile.ImportAlias(importPath); got != alias {
+			t.Errorf("ImportAlias(%q) = %q, want %q", importPath, got, alias)
+		}
+	}
+	var nilFile *SrcFile
+	if nilFile.Imports() != nil || nilFile.ImportAlias("fmt") != "" {
+		t.Errorf("nil SrcFile has imports")
+	}
+}
+
+func TestSrcFileImportFlags(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import (
+	"os"
+	fp "path/filepath"
+	. "fmt"
+	_ "net/http/pprof"
+)
+
+var _ = Sprint(os.Args, fp.Separator)
+`})
+	var want = []ImportSpec{
+		{Path: "os"},
+		{Path: "path/filepath", Alias: "fp"},
+		{Path: "fmt", Alias: ".", Dot: true},
+		{Path: "net/http/pprof", Alias: "_", Blank: true},
+	}
+	if got := pkg.SrcFile("p.go").Imports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Imports = %+v, want %+v", got, want)
+	}
+}
+
+func TestPackageDoc(t *testing.T) {
+	const doc = "Package p implements the fixture\nof package documentation.\n"
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go":   "// Package p is documented in a.go.\npackage p\n",
+		"b.go":   "// Copyright is not attached to the package clause.\n\npackage p\n",
+		"doc.go": "// Package p implements the fixture\n// of package documentation.\npackage p\n",
+	})
+	if got := pkg.SrcFile("doc.go").DocComment(); got != doc {
+		t.Errorf("DocComment of doc.go = %q, want %q", got, doc)
+	}
+	if got := pkg.SrcFile("b.go").DocComment(); got != "" {
+		t.Errorf("DocComment of b.go = %q, want empty", got)
+	}
+	if got := pkg.Doc(); got != doc {
+		t.Errorf("Doc = %q, want the one of doc.go %q", got, doc)
+	}
+
+	pkg = mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n",
+		"b.go": "/*\nPackage p is documented\nin b.go.\n*/\npackage p\n",
+	})
+	if got, want := pkg.Doc(), "Package p is documented\nin b.go.\n"; got != want {
+		t.Errorf("Doc = %q, want %q", got, want)
+	}
+	if got := mustVirtualPackage(t, map[string]string{"a.go": "package p\n"}).Doc(); got != "" {
+		t.Errorf("Doc of undocumented package = %q, want empty", got)
+	}
+}
+
+func TestSrcFileNodeAt(t *testing.T) {
+	const code = "package p\n\nvar total = compute()\n\nvar pair = []int{1, 2}\n\nfunc compute() int { return 0 }\n"
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": code})
+	srcFile := pkg.SrcFile("p.go")
+	var base = pkg.FileSet().File(srcFile.Syntax().Pos()).Base()
+	var posOf = func(text string, offset int) token.Pos {
+		return token.Pos(base + strings.Index(code, text) + offset)
+	}
+
+	ident, ok := srcFile.NodeAt(posOf("total", 2)).(*ast.Ident)
+	if !ok || ident.Name != "total" {
+		t.Errorf("NodeAt(total) = %#v, want the identifier total", srcFile.NodeAt(posOf("total", 2)))
+	}
+	if lit, ok := srcFile.NodeAt(posOf("{1, 2}", 3)).(*ast.CompositeLit); !ok || len(lit.Elts) != 2 {
+		t.Errorf("NodeAt(space in literal) = %#v, want the composite literal", srcFile.NodeAt(posOf("{1, 2}", 3)))
+	}
+	if lit, ok := srcFile.NodeAt(posOf("2}", 0)).(*ast.BasicLit); !ok || lit.Value != "2" {
+		t.Errorf("NodeAt(2) = %#v, want the basic literal 2", srcFile.NodeAt(posOf("2}", 0)))
+	}
+	if _, ok := srcFile.NodeAt(posOf("compute()", 0)).(*ast.Ident); !ok {
+		t.Errorf("NodeAt(compute) = %#v, want an identifier", srcFile.NodeAt(posOf("compute()", 0)))
+	}
+	if node := srcFile.NodeAt(token.Pos(base + len(code) + 10)); node != nil {
+		t.Errorf("NodeAt(out of file) = %#v, want nil", node)
+	}
+	if node := srcFile.NodeAt(token.NoPos); node != nil {
+		t.Errorf("NodeAt(NoPos) = %#v, want nil", node)
+	}
+}
+
+func TestSrcFileFunctions(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+type T struct{}
+
+func First() { _ = func() {} }
+
+func (T) Method() {}
+
+var literal = func() int { return 0 }
+
+func Second() {}
+`})
+	var names []string
+	for _, funcDecl := range pkg.SrcFile("p.go").Functions() {
+		names = append(names, funcNameOf(funcDecl))
+	}
+	if want := []string{"First", "T.Method", "Second"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Functions = %v, want %v", names, want)
+	}
+}
+
+// linesOf returns the lines of the nodes in the file.
+func linesOf[N ast.Node](file *SrcFile, nodes []N) []int {
+	var lines []int
+	for _, node := range nodes {
+		line, _, _ := file.LineColumn(node.Pos())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestSrcFileDefers(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "sync"
+
+func Close(mu *sync.Mutex, items []func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, item := range items {
+		defer item()
+		func() {
+			defer item()
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if i > 1 {
+			defer mu.Unlock()
+		}
+	}
+}
+`})
+	srcFile := pkg.SrcFile("p.go")
+	if got, want := linesOf(srcFile, srcFile.DeferStatements()), []int{7, 9, 11, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of DeferStatements = %v, want %v", got, want)
+	}
+	if got, want := linesOf(srcFile, srcFile.DefersInsideLoops()), []int{9, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of DefersInsideLoops = %v, want %v", got, want)
+	}
+}
+
+func TestSrcFileNamedReturns(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+func Named() (n int, err error) {
+	defer func() {
+		if err != nil {
+			return
+		}
+	}()
+	if n > 0 {
+		return
+	}
+	return 1, nil
+}
+
+func Blank() (_ int) { return 0 }
+
+func Unnamed() int { return 0 }
+
+func (*T) Method() (ok bool) {
+	return
+}
+
+type T struct{}
+`})
+	srcFile := pkg.SrcFile("p.go")
+	var names []string
+	for _, funcDecl := range srcFile.NamedReturns() {
+		names = append(names, funcNameOf(funcDecl))
+	}
+	if want := []string{"Named", "(*T).Method"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("NamedReturns = %v, want %v", names, want)
+	}
+	if got, want := linesOf(srcFile, srcFile.NakedReturns()), []int{10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of NakedReturns = %v, want %v", got, want)
+	}
+}
+
+func TestPackageBenchmarksAndExamples(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"p.go": "package p\n\nimport \"testing\"\n\nfunc BenchmarkNotTest(b *testing.B) {}\n\nfunc ExampleNotTest() {}\n",
+		"p_test.go": `package p
+
+import "testing"
+
+func BenchmarkFoo(b *testing.B) {}
+
+func Benchmarkfoo(b *testing.B) {}
+
+func BenchmarkWrongParam(t *testing.T) {}
+
+func Benchmark(b *testing.B) {}
+
+func ExampleFoo() {}
+
+func Example_suffix() {}
+
+func ExampleWithParam(n int) {}
+
+func Examplefoo() {}
+`,
+	})
+	var namesOf = func(funcDecls []*ast.FuncDecl) []string {
+		var names []string
+		for _, funcDecl := range funcDecls {
+			names = append(names, funcDecl.Name.Name)
+		}
+		return names
+	}
+	if got, want := namesOf(pkg.Benchmarks()), []string{"BenchmarkFoo", "Benchmark"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Benchmarks = %v, want %v", got, want)
+	}
+	if got, want := namesOf(pkg.ExampleFunctions()), []string{"ExampleFoo", "Example_suffix"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExampleFunctions = %v, want %v", got, want)
+	}
+}
+
+func TestPackageInitFunctions(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nvar A = B + 1\n\nfunc init() {}\n\nfunc init() { A++ }\n",
+		"b.go": "package p\n\nvar B = 1\n\ntype T struct{}\n\nfunc (T) init() {}\n",
+		"c.go": "package p\n\nfunc init() {}\n",
+	})
+	var counts = make(map[string]int)
+	for path, initFunctions := range pkg.InitFunctions() {
+		counts[path] = len(initFunctions)
+	}
+	if want := map[string]int{"a.go": 2, "c.go": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts of InitFunctions = %v, want %v", counts, want)
+	}
+	if got := pkg.SrcFile("b.go").InitFunctions(); len(got) != 0 {
+		t.Errorf("InitFunctions of b.go = %v, want none", got)
+	}
+	var order []string
+	for _, initializer := range pkg.InitOrder() {
+		order = append(order, initializer.String())
+	}
+	if want := []string{"B = 1", "A = B + 1"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("InitOrder = %v, want %v", order, want)
+	}
+}
+
+func TestSrcFileGlobals(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{"p.go": `package p
+
+import "io"
+
+var A = 1
+
+var (
+	B, C int
+	_    = A
+	_, D = io.EOF, 2
+)
+
+const E = "e"
+
+const (
+	F = iota
+	_
+)
+
+func f() {
+	var local = 1
+	const localConst = 2
+	_ = local
+}
+`})
+	var namesOf = func(valueSpecs []*ast.ValueSpec) []string {
+		var names []string
+		for _, valueSpec := range valueSpecs {
+			for _, name := range valueSpec.Names {
+				names = append(names, name.Name)
+			}
+		}
+		return names
+	}
+	srcFile := pkg.SrcFile("p.go")
+	if got, want := namesOf(srcFile.GlobalVars()), []string{"A", "B", "C", "_", "D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GlobalVars = %v, want %v", got, want)
+	}
+	if got, want := namesOf(srcFile.GlobalConsts()), []string{"E", "F"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GlobalConsts = %v, want %v", got, want)
+	}
+	var nilFile *SrcFile
+	if nilFile.GlobalVars() != nil || nilFile.GlobalConsts() != nil {
+		t.Errorf("nil SrcFile has globals")
+	}
+}
+
+func TestSrcFileAnnotations(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"p.go": "package p\n\n// Deprecated: use G instead.\nfunc F() {}\n\n/*\n * TODO: split this\n * FIXME:fix it */\n" +
+			"func G() {} // NOTE: nothing\n\n// Todo: lower case is ignored\n// HACK:\nvar V = 1\n",
+	})
+	var srcFile = pkg.SrcFile("p.go")
+	var got []string
+	for _, annotation := range srcFile.Annotations() {
+		got = append(got, fmt.Sprintf("%d:%d %s %q", annotation.Pos.Line, annotation.Pos.Column,
+			annotation.Kind, annotation.Text))
+	}
+	var want = []string{
+		`3:4 Deprecated "use G instead."`,
+		`7:4 TODO "split this"`,
+		`8:4 FIXME "fix it"`,
+		`9:16 NOTE "nothing"`,
+		`12:4 HACK ""`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations = %q, want %q", got, want)
+	}
+	if annotations := srcFile.AnnotationsOf("Todo"); len(annotations) != 1 || annotations[0].Text != "lower case is ignored" {
+		t.Errorf("AnnotationsOf(Todo) = %v, want the line 11", annotations)
+	}
+	if annotations := newSrcFile(nil, "a.go").Annotations(); annotations != nil {
+		t.Errorf("Annotations without syntax = %v, want nil", annotations)
+	}
+}
+
+func TestSelectStatements(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": `package p
+
+func Wait(a, b chan int, done chan struct{}) int {
+	select {
+	case v := <-a:
+		return v
+	case v := <-b:
+		return v
+	}
+	select {
+	case <-done:
+	}
+	select {
+	case a <- 1:
+	default:
+	}
+	select {}
+}
+`,
+		"b.go": "package p\n\nfunc Recv(c chan int) int {\n\tselect {\n\tcase v := <-c:\n\t\treturn v\n\t}\n}\n",
+	})
+	var a, b = pkg.SrcFile("a.go"), pkg.SrcFile("b.go")
+	if got, want := linesOf(a, a.SelectStatements()), []int{4, 10, 13, 17}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of SelectStatements = %v, want %v", got, want)
+	}
+	if got, want := linesOf(a, a.BlockingSelects()), []int{4, 10, 17}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of BlockingSelects = %v, want %v", got, want)
+	}
+	var singles = pkg.SingleCaseSelects()
+	if len(singles) != 2 || singles[0] != a.SelectStatements()[1] || singles[1] != b.SelectStatements()[0] {
+		t.Errorf("SingleCaseSelects = %v, want the selects at a.go:10 and b.go:4", singles)
+	}
+}
+
+func TestGenericFunctions(t *testing.T) {
+	pkg := mustVirtualPackage(t, map[string]string{
+		"a.go": "package p\n\nfunc Map[T, U any](xs []T, f func(T) U) []U { return nil }\n\n" +
+			"type Box[T any] struct{ V T }\n\nfunc (b Box[T]) Get() T { return b.V }\n\nfunc Plain() {}\n",
+		"b.go": "package p\n\nfunc Keys[K comparable, V any](m map[K]V) []K { return nil }\n\n" +
+			"var _ = Map([]int{1}, func(int) string { return \"\" })\n\nvar _ Box[string]\n",
+	})
+	var names []string
+	for _, funcDecl := range pkg.GenericFunctions() {
+		names = append(names, funcDecl.Name.Name)
+	}
+	if want := []string{"Map", "Keys"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("GenericFunctions = %v, want %v", names, want)
+	}
+	var typeParams = pkg.TypeParameters()
+	if len(typeParams) != 2 || len(typeParams["Map"]) != 1 || len(typeParams["Map"][0].Names) != 2 ||
+		len(typeParams["Keys"]) != 2 {
+		t.Errorf("TypeParameters = %v, want the fields of Map and Keys", typeParams)
+	}
+
+	var b = pkg.SrcFile("b.go")
+	var instances []string
+	for _, instance := range b.InstantiatedGenerics() {
+		var typeArgs []string
+		for index := 0; index < instance.TypeArgs.Len(); index++ {
+			typeArgs = append(typeArgs, instance.TypeArgs.At(index).String())
+		}
+		instances = append(instances, fmt.Sprint(typeArgs))
+	}
+	if want := []string{"[int string]", "[string]"}; !reflect.DeepEqual(instances, want) {
+		t.Errorf("InstantiatedGenerics of b.go = %v, want %v", instances, want)
+	}
+	if got := pkg.SrcFile("a.go").InstantiatedGenerics(); len(got) != 1 || got[0].Type.String() != "example.com/p.Box[T]" {
+		t.Errorf("InstantiatedGenerics of a.go = %v, want Box[T] of the receiver", got)
+	}
+}
@@ -0,0 +1,4 @@
+package p
+
+// Common is declared on all platforms.
+const Common = 1
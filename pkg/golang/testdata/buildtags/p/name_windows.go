@@ -0,0 +1,4 @@
+package p
+
+// ByName is declared on windows by the file name.
+const ByName = true
@@ -0,0 +1,6 @@
+//go:build linux
+
+package p
+
+// OS is the target operating system.
+const OS = "linux"
@@ -0,0 +1,6 @@
+//go:build windows
+
+package p
+
+// OS is the target operating system.
+const OS = "windows"
@@ -0,0 +1,6 @@
+package app
+
+import "example.com/dep"
+
+// Version is reported by the vendored copy of dep.
+var Version string = dep.Vendored()